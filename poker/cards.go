@@ -1,16 +1,15 @@
 package poker
 
 import (
+    "errors"
     "fmt"
-    crand "crypto/rand"
     "html/template"
-    "math/big"
-    "math/rand"
-    "time"
+    "strings"
 )
 
-var r = rand.New(rand.NewSource(time.Now().UnixNano()))
-
+// A Card from zero up is a playing card: Card % 52 is its face in a standard
+// deck and Card / 52 is which copy of the deck it came from, so a single deck
+// is just 0 to 51.  A Card below zero is a joker, see Joker.
 type Card int
 type Suit int
 type Rank int
@@ -29,6 +28,7 @@ const (
     AceLowStraightFlush
     StraightFlush
     Royal
+    FiveOfAKind  // only with jokers or several decks
 )
 
 type Royalty struct {
@@ -63,50 +63,165 @@ func (r Royalty) String() string {
             rs = "Straight Flush"
         case Royal:
             rs = "Royal Flush"
+        case FiveOfAKind:
+            rs = "Five of a Kind"
     }
     return fmt.Sprintf("%s (%v)", rs, r.Cards)
 }
 
-func NewOrderedDeck() Deck {
-    d := Deck(make([]Card, 52))
-    for i, _ := range d {
-        d[i] = Card(i)
+// DeckSpec describes which cards make up the deck.  Low is the lowest rank
+// dealt: 0 (deuces) for the standard 52 card deck, 4 (sixes) for the 36 card
+// short deck.  Hands dealt from a short deck are ranked by short deck rules,
+// see NewHand.  Decks copies of those cards are shuffled together (zero
+// means one) along with Jokers jokers, which are wild.
+type DeckSpec struct {
+    Low Rank
+    Decks int
+    Jokers int
+}
+
+const (
+    MaxDecks = 4
+    MaxJokers = 9
+)
+
+var (
+    StandardDeck = DeckSpec{Low: 0}
+    ShortDeck = DeckSpec{Low: 4}
+)
+
+// Joker returns the nth joker, counting from zero.
+func Joker(n int) Card {
+    return Card(-1 - n)
+}
+
+func (c Card) IsJoker() bool {
+    return c < 0
+}
+
+// Face returns the card as it appears in the first deck.
+func (c Card) Face() Card {
+    if c.IsJoker() {
+        return c
     }
-    return d
+    return c % 52
 }
 
-func nextInt(n int) int {
-    i, err := crand.Int(crand.Reader, big.NewInt(int64(n)))
-    if err != nil {
-        return r.Intn(n)
+func (ds DeckSpec) decks() int {
+    if ds.Decks < 1 {
+        return 1
     }
-    return int(i.Int64())
+    return ds.Decks
 }
 
-func NewShuffledDeck() Deck {
-    d := NewOrderedDeck()
+func (ds DeckSpec) Valid() error {
+    if ds.Low < 0 || ds.Low > 8 {
+        return errors.New("Bad lowest rank")
+    }
+    if ds.Decks < 0 || ds.Decks > MaxDecks {
+        return fmt.Errorf("Use at most %d decks", MaxDecks)
+    }
+    if ds.Jokers < 0 || ds.Jokers > MaxJokers {
+        return fmt.Errorf("Use at most %d jokers", MaxJokers)
+    }
+    return nil
+}
+
+// String names the deck, e.g. "Short deck" or "Standard deck x2, 2 jokers".
+func (ds DeckSpec) String() string {
+    name := "Standard deck"
+    if ds.Short() {
+        name = "Short deck"
+    }
+    if ds.decks() > 1 {
+        name = fmt.Sprintf("%s x%d", name, ds.decks())
+    }
+    if ds.Jokers == 1 {
+        name += ", 1 joker"
+    } else if ds.Jokers > 1 {
+        name += fmt.Sprintf(", %d jokers", ds.Jokers)
+    }
+    return name
+}
+
+func (ds DeckSpec) Short() bool {
+    return ds.Low > 0
+}
+
+func (ds DeckSpec) Size() int {
+    return ds.decks() * 4 * (13 - int(ds.Low)) + ds.Jokers
+}
+
+func (ds DeckSpec) Contains(c Card) bool {
+    if c.IsJoker() {
+        return int(-1 - c) < ds.Jokers
+    }
+    return int(c) < 52 * ds.decks() && c.Rank() >= ds.Low
+}
+
+func (ds DeckSpec) Ordered() Deck {
+    d := Deck(make([]Card, 0, ds.Size()))
+    for i := 0; i < 52 * ds.decks(); i++ {
+        if ds.Contains(Card(i)) {
+            d = append(d, Card(i))
+        }
+    }
+    for i := 0; i < ds.Jokers; i++ {
+        d = append(d, Joker(i))
+    }
+    return d
+}
+
+func (ds DeckSpec) Shuffled(src RandSource) (Deck, error) {
+    d := ds.Ordered()
     for i, _ := range d {
-        j := i + nextInt(52 - i)
+        j, err := intn(src, len(d) - i)
+        if err != nil {
+            return nil, err
+        }
+        j += i
         temp := d[i]
         d[i] = d[j]
         d[j] = temp
     }
-    return d
+    return d, nil
+}
+
+// NewHand ranks cards by the rules that go with this deck.
+func (ds DeckSpec) NewHand(cards []Card) *Hand {
+    return newHand(cards, ds.Low)
+}
+
+func NewOrderedDeck() Deck {
+    return StandardDeck.Ordered()
+}
+
+func NewShuffledDeck(src RandSource) (Deck, error) {
+    return StandardDeck.Shuffled(src)
 }
 
 func (c Card) Suit() Suit {
-    return Suit(c / 13)
+    if c.IsJoker() {
+        return -1
+    }
+    return Suit(c % 52 / 13)
 }
 
 func (c Card) String() string {
+    if c.IsJoker() {
+        return "Joker"
+    }
     return fmt.Sprintf("%s%s", c.Rank(), c.Suit())
 }
 
 func (c Card) HTML() template.HTML {
-    return template.HTML(fmt.Sprintf("%s%s", c.Rank(), c.Suit()))
+    return template.HTML(c.String())
 }
 
 func (c Card) Rank() Rank {
+    if c.IsJoker() {
+        return -1
+    }
     return Rank(int(c) % 13)
 }
 
@@ -140,4 +255,118 @@ func (s Suit) String() string {
             return "<font color=red>\u2666</font>"
     }
     return "ERROR"
+}
+
+func (r Rank) Char() string {
+    return string("23456789TJQKA"[r])
+}
+
+func (s Suit) Char() string {
+    return string("schd"[s])
+}
+
+// Code returns the plain text form of a card, e.g. "As" or "Td", or "X1"
+// for the first joker.  Copies of a card from different decks share a code.
+func (c Card) Code() string {
+    if c.IsJoker() {
+        return fmt.Sprintf("X%d", -c)
+    }
+    return c.Rank().Char() + c.Suit().Char()
+}
+
+func ParseRank(b byte) (Rank, error) {
+    if i := strings.IndexByte("23456789TJQKA", upper(b)); i >= 0 {
+        return Rank(i), nil
+    }
+    return 0, fmt.Errorf("Unknown rank '%c'", b)
+}
+
+func ParseSuit(b byte) (Suit, error) {
+    if i := strings.IndexByte("SCHD", upper(b)); i >= 0 {
+        return Suit(i), nil
+    }
+    return 0, fmt.Errorf("Unknown suit '%c'", b)
+}
+
+func upper(b byte) byte {
+    if b >= 'a' && b <= 'z' {
+        return b - 'a' + 'A'
+    }
+    return b
+}
+
+func NewCard(r Rank, s Suit) Card {
+    return Card(int(s) * 13 + int(r))
+}
+
+// ParseCard reads a card written as rank then suit, e.g. "As", "td" or "10h",
+// or a joker written as "X1" to "X9".  Cards are always from the first deck.
+func ParseCard(s string) (Card, error) {
+    if len(s) == 3 && s[:2] == "10" {
+        s = "T" + s[2:]
+    }
+    if len(s) != 2 {
+        return 0, fmt.Errorf("Bad card \"%s\"", s)
+    }
+    if upper(s[0]) == 'X' && s[1] >= '1' && s[1] <= '0' + MaxJokers {
+        return Joker(int(s[1] - '1')), nil
+    }
+    r, err := ParseRank(s[0])
+    if err != nil {
+        return 0, err
+    }
+    suit, err := ParseSuit(s[1])
+    if err != nil {
+        return 0, err
+    }
+    return NewCard(r, suit), nil
+}
+
+// ParseCards reads a list of cards such as "AsKd", "As Kd" or "As,Kd".
+func ParseCards(s string) ([]Card, error) {
+    cards := make([]Card, 0)
+    for i := 0; i < len(s); {
+        switch s[i] {
+            case ' ', ',', '\t', '\r', '\n':
+                i++
+                continue
+        }
+        n := 2
+        if i + 3 <= len(s) && s[i:i+2] == "10" {
+            n = 3
+        }
+        if i + n > len(s) {
+            return nil, fmt.Errorf("Bad card \"%s\"", s[i:])
+        }
+        card, err := ParseCard(s[i:i+n])
+        if err != nil {
+            return nil, err
+        }
+        cards = append(cards, card)
+        i += n
+    }
+    return cards, nil
+}
+
+// check returns an error if any card is not in the deck or appears more
+// than once.
+func (ds DeckSpec) check(cards []Card) error {
+    for _, card := range cards {
+        if !ds.Contains(card) {
+            return errors.New("Card " + card.Code() + " is not in the deck")
+        }
+    }
+    return checkDistinct(cards)
+}
+
+// checkDistinct returns an error if any card appears more than once.
+func checkDistinct(cards []Card) error {
+    seen := make(map[Card]bool)
+    for _, card := range cards {
+        if seen[card] {
+            return errors.New("Card " + card.Code() + " is used more than once")
+        }
+        seen[card] = true
+    }
+    return nil
 }
\ No newline at end of file
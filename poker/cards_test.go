@@ -0,0 +1,77 @@
+package poker
+
+import (
+    "testing"
+)
+
+func TestJokers(t *testing.T) {
+    tests := []struct {
+        cards string
+        rank int
+        subs string  // what the jokers stand for
+    }{
+        {"X1AsAhAdKc", Quads, "Ac"},
+        {"X1AsKsQsJs", Royal, "Ts"},
+        {"X19sKhQdJc", Straight, "Ts"},
+        {"X12h5h9hJh", Flush, "Ah"},
+        {"X17s7h2d2c", FullHouse, "7d"},
+        // Jokers may copy a card already in the hand.
+        {"X1X2AsAhAd", FiveOfAKind, "AsAs"},
+        {"X1AsAh", Trips, "As"},
+        {"X12s3h4d", Pair, "4s"},
+    }
+    for _, test := range tests {
+        h := NewHand(cardsOf(t, test.cards))
+        subs := ""
+        for _, c := range h.Subs {
+            subs += c.Code()
+        }
+        if h.Royalty.Rank != test.rank || subs != test.subs || len(h.Wild) != len(h.Subs) {
+            t.Errorf("%s ranked %v with %s for %v", test.cards, h.Royalty, subs, h.Wild)
+        }
+    }
+    // A joker plays as the card it stands for.
+    wild := NewHand(cardsOf(t, "X1AsAhAdKc"))
+    if c := wild.Compare(NewHand(cardsOf(t, "AsAhAdAcKc"))); c != 0 {
+        t.Errorf("wild quads against natural quads: %d", c)
+    }
+    if c := wild.Compare(NewHand(cardsOf(t, "KsKhKdKcAc"))); c <= 0 {
+        t.Errorf("wild aces against natural kings: %d", c)
+    }
+}
+
+func TestMultiDeck(t *testing.T) {
+    spec := DeckSpec{Decks: 2, Jokers: 2}
+    as := NewCard(12, 0)
+    second := as + 52
+    if spec.Size() != 106 || len(spec.Ordered()) != 106 {
+        t.Errorf("size %d, ordered %d", spec.Size(), len(spec.Ordered()))
+    }
+    if !spec.Contains(second) || StandardDeck.Contains(second) || !spec.Contains(Joker(1)) || spec.Contains(Joker(2)) {
+        t.Error("wrong cards in the deck")
+    }
+    if second.Face() != as || second.Code() != "As" {
+        t.Errorf("the second ace of spades shows as %s", second.Code())
+    }
+    // The copies are different cards, but either copy twice is a mistake.
+    if err := spec.check([]Card{as, second}); err != nil {
+        t.Error(err)
+    }
+    if err := spec.check([]Card{second, second}); err == nil {
+        t.Error("the same copy was used twice")
+    }
+    tests := []struct {
+        cards []Card
+        rank int
+    }{
+        {[]Card{as, second, NewCard(11, 3), NewCard(10, 1), NewCard(0, 2)}, Pair},
+        {[]Card{as, second, as + 104, as + 156, NewCard(12, 2)}, FiveOfAKind},
+        // A flush with a pair in it is still a flush.
+        {[]Card{as, second, NewCard(11, 0), NewCard(10, 0), NewCard(0, 0)}, Flush},
+    }
+    for _, test := range tests {
+        if h := NewHand(test.cards); h.Royalty.Rank != test.rank {
+            t.Errorf("%v ranked %v", test.cards, h.Royalty)
+        }
+    }
+}
@@ -0,0 +1,123 @@
+package poker
+
+// CardSet is a set of cards from a single deck, plus jokers, packed one bit
+// per card: bit c for card c in 0..51 and bit 52 + n for the nth joker.
+// Cards from a second or later deck cannot be stored; use Fits to check.
+type CardSet uint64
+
+const (
+    jokerBit = 52
+    allCards = CardSet(1 << 52 - 1)
+    rankBits = 1 << 13 - 1
+)
+
+// Fits reports whether c can be stored in a CardSet.
+func Fits(c Card) bool {
+    return (c >= 0 && c < 52) || (c.IsJoker() && int(-1 - c) < MaxJokers)
+}
+
+func bit(c Card) CardSet {
+    if c.IsJoker() {
+        return 1 << uint(jokerBit - 1 - int(c))
+    }
+    return 1 << uint(c)
+}
+
+// NewCardSet returns the set of cards, or false if any of them do not fit.
+func NewCardSet(cards []Card) (CardSet, bool) {
+    var s CardSet
+    for _, card := range cards {
+        if !Fits(card) {
+            return 0, false
+        }
+        s |= bit(card)
+    }
+    return s, true
+}
+
+func (d Deck) Set() (CardSet, bool) {
+    return NewCardSet(d)
+}
+
+func (s CardSet) Add(c Card) CardSet {
+    return s | bit(c)
+}
+
+func (s CardSet) Remove(c Card) CardSet {
+    return s &^ bit(c)
+}
+
+func (s CardSet) Contains(c Card) bool {
+    return Fits(c) && s & bit(c) != 0
+}
+
+func (s CardSet) Union(other CardSet) CardSet {
+    return s | other
+}
+
+func (s CardSet) Intersect(other CardSet) CardSet {
+    return s & other
+}
+
+func (s CardSet) Minus(other CardSet) CardSet {
+    return s &^ other
+}
+
+func (s CardSet) Count() int {
+    n := 0
+    for ; s != 0; s &= s - 1 {
+        n++
+    }
+    return n
+}
+
+// Each calls f on every card in the set, lowest bit first: the spades deuce
+// to ace, then clubs, hearts, diamonds and finally the jokers.
+func (s CardSet) Each(f func(c Card)) {
+    for i := uint(0); s >> i != 0; i++ {
+        if s & (1 << i) == 0 {
+            continue
+        }
+        if i < jokerBit {
+            f(Card(i))
+        } else {
+            f(Joker(int(i) - jokerBit))
+        }
+    }
+}
+
+func (s CardSet) Cards() []Card {
+    cards := make([]Card, 0, s.Count())
+    s.Each(func(c Card) {
+        cards = append(cards, c)
+    })
+    return cards
+}
+
+func (s CardSet) Deck() Deck {
+    return Deck(s.Cards())
+}
+
+// SuitMask returns the cards of suit suit as a set.
+func SuitMask(suit Suit) CardSet {
+    return rankBits << uint(13 * suit)
+}
+
+// RankMask returns the four cards of rank r as a set.
+func RankMask(r Rank) CardSet {
+    return (1 | 1 << 13 | 1 << 26 | 1 << 39) << uint(r)
+}
+
+func (s CardSet) Jokers() int {
+    return (s >> jokerBit).Count()
+}
+
+// Ranks returns one bit for each rank in the set, deuce lowest.
+func (s CardSet) Ranks() int {
+    return int((s | s >> 13 | s >> 26 | s >> 39) & rankBits)
+}
+
+// Suit returns the ranks of the cards of one suit, deuce lowest.
+func (s CardSet) Suit(suit Suit) int {
+    return int(s >> uint(13 * suit) & rankBits)
+}
@@ -0,0 +1,118 @@
+package poker
+
+import (
+    "testing"
+)
+
+func TestCardSet(t *testing.T) {
+    cards := cardsOf(t, "2sAsKh3dX2")
+    set, ok := NewCardSet(cards)
+    if !ok || set.Count() != 5 || set.Jokers() != 1 {
+        t.Fatalf("set of %v: %b, %v", cards, set, ok)
+    }
+    for _, c := range cards {
+        if !set.Contains(c) || set.Remove(c).Contains(c) {
+            t.Errorf("%s in and out of the set", c.Code())
+        }
+    }
+    if set.Contains(NewCard(12, 1)) || set.Contains(Joker(0)) || set.Contains(NewCard(0, 0) + 52) {
+        t.Error("holds cards never added")
+    }
+    // Cards come out a suit at a time, deuce to ace, then the jokers.
+    got := ""
+    for _, c := range set.Cards() {
+        got += c.Code()
+    }
+    if got != "2sAsKh3dX2" {
+        t.Errorf("cards in order %s", got)
+    }
+    other, _ := NewCardSet(cardsOf(t, "AsAh"))
+    if n := set.Union(other).Count(); n != 6 {
+        t.Errorf("union of %d", n)
+    }
+    if i := set.Intersect(other); i.Count() != 1 || !i.Contains(NewCard(12, 0)) {
+        t.Errorf("intersection %v", i.Cards())
+    }
+    if m := set.Minus(other); m.Count() != 4 || m.Contains(NewCard(12, 0)) {
+        t.Errorf("difference %v", m.Cards())
+    }
+    if set.Ranks() != 1 << 12 | 1 << 11 | 1 << 1 | 1 {
+        t.Errorf("ranks %b", set.Ranks())
+    }
+    if set.Suit(0) != 1 << 12 | 1 || set.Suit(1) != 0 {
+        t.Errorf("spades %b, clubs %b", set.Suit(0), set.Suit(1))
+    }
+    if set.Intersect(SuitMask(2)).Count() != 1 || set.Intersect(RankMask(12)).Count() != 1 {
+        t.Error("masks")
+    }
+    if d, ok := StandardDeck.Ordered().Set(); !ok || d != allCards || len(d.Deck()) != 52 {
+        t.Errorf("full deck %b", d)
+    }
+    if _, ok := NewCardSet([]Card{NewCard(0, 0) + 52}); ok {
+        t.Error("stored a card from the second deck")
+    }
+}
+
+// sameHand reports whether two hands were laid out card for card alike.
+func sameHand(a, b *Hand) bool {
+    if a.Royalty.Rank != b.Royalty.Rank || len(a.Royalty.Cards) != len(b.Royalty.Cards) || len(a.Kickers) != len(b.Kickers) {
+        return false
+    }
+    for i, c := range a.Royalty.Cards {
+        if c != b.Royalty.Cards[i] {
+            return false
+        }
+    }
+    for i, c := range a.Kickers {
+        if c != b.Kickers[i] {
+            return false
+        }
+    }
+    return true
+}
+
+// The masks give the same hands as working through the cards, for every
+// three cards and for a sample of five.
+func TestRankSet(t *testing.T) {
+    check := func(cards []Card, low Rank) {
+        cards = sortedCards(cards)
+        set, _ := NewCardSet(cards)
+        if a, b := rankSet(cards, set, low), rankCards(cards, low); !sameHand(a, b) {
+            t.Errorf("%v: masks made %v, cards %v", cards, a, b)
+        }
+    }
+    for _, spec := range []DeckSpec{StandardDeck, ShortDeck} {
+        deck := spec.Ordered()
+        for i := range deck {
+            for j := i + 1; j < len(deck); j++ {
+                for k := j + 1; k < len(deck); k++ {
+                    check([]Card{deck[i], deck[j], deck[k]}, spec.Low)
+                }
+            }
+        }
+        for n := 0; n < 20000; n++ {
+            d, _ := spec.Shuffled(SeededSource(int64(n)))
+            check(append([]Card{}, d[:5]...), spec.Low)
+        }
+    }
+}
+
+func benchmarkRank(b *testing.B, rank func([]Card, Rank) *Hand) {
+    hands := make([][]Card, 1000)
+    for i := range hands {
+        d, _ := StandardDeck.Shuffled(SeededSource(int64(i)))
+        hands[i] = sortedCards(append([]Card{}, d[:5]...))
+    }
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        rank(hands[i % len(hands)], 0)
+    }
+}
+
+func BenchmarkRankHand(b *testing.B) {
+    benchmarkRank(b, rankHand)
+}
+
+func BenchmarkRankCards(b *testing.B) {
+    benchmarkRank(b, rankCards)
+}
@@ -0,0 +1,156 @@
+package poker
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/hex"
+    "errors"
+    "strings"
+    "time"
+    "unicode/utf8"
+)
+
+// Chat limits.  A player may send ChatBurst messages in any ChatWindow.
+const (
+    MaxChatLength = 300
+    ChatBurst = 5
+    ChatWindow = 10 * time.Second
+    chatHistory = 100  // messages kept with the game
+    maxMutes = 50  // senders each player may have muted
+)
+
+// Emotes are the canned reactions a player can send.
+var Emotes = []string{"gg", "nice hand", "wow", "ouch", "thanks", "hurry up"}
+
+// ChatMessage is one line of table chat.  Sender identifies who sent it, to
+// mute them by, without giving away their address.
+type ChatMessage struct {
+    Sender string
+    Name string
+    Text string
+    Emote bool
+    Spectator bool  // sent by a spectator, and only shown to spectators
+    Time time.Time
+}
+
+// storedChat is a message as kept with the game, with the id of its sender,
+// which is never sent to clients.
+type storedChat struct {
+    ChatMessage
+    From string
+}
+
+// chatSender keys id with the game's secret ChatKey, so that the address
+// behind a Sender cannot be found by hashing guesses.
+func (gs *GameState) chatSender(id string) (string, error) {
+    if gs.ChatKey == "" {
+        key, err := NewServerSeed(gs.source())
+        if err != nil {
+            return "", err
+        }
+        gs.ChatKey = key
+    }
+    mac := hmac.New(sha256.New, []byte(gs.ChatKey))
+    mac.Write([]byte(id))
+    return hex.EncodeToString(mac.Sum(nil)[:6]), nil
+}
+
+// Say adds a message from id to the table chat.
+func (gs *GameState) Say(id, name, text string) error {
+    text = strings.TrimSpace(text)
+    if text == "" {
+        return errors.New("Say something")
+    }
+    if utf8.RuneCountInString(text) > MaxChatLength {
+        return errors.New("Message is too long")
+    }
+    return gs.chat(id, name, text, false)
+}
+
+// Emote sends one of the Emotes.
+func (gs *GameState) Emote(id, name, emote string) error {
+    for _, e := range Emotes {
+        if e == emote {
+            return gs.chat(id, name, emote, true)
+        }
+    }
+    return errors.New("Unknown emote")
+}
+
+func (gs *GameState) chat(id, name, text string, emote bool) error {
+    now := gs.now()
+    sent := 0
+    for _, m := range gs.Chat {
+        if m.From == id && now.Sub(m.Time) < ChatWindow {
+            sent++
+        }
+    }
+    if sent >= ChatBurst {
+        return errors.New("You are sending messages too quickly")
+    }
+    spectator := true
+    if seat := gs.seatOf(id); seat >= 0 {
+        spectator = false
+        name = gs.PlayerNames[seat]
+    }
+    if name == "" {
+        name = "Spectator"
+    }
+    sender, err := gs.chatSender(id)
+    if err != nil {
+        return err
+    }
+    gs.Chat = append(gs.Chat, storedChat{
+        ChatMessage: ChatMessage{Sender: sender, Name: name, Text: text, Emote: emote,
+            Spectator: spectator, Time: now},
+        From: id,
+    })
+    if len(gs.Chat) > chatHistory {
+        gs.Chat = gs.Chat[len(gs.Chat) - chatHistory:]
+    }
+    return nil
+}
+
+// Mute hides, or with muted false shows again, the messages of sender from
+// the player by.  Only a sender in the chat can be muted, and past maxMutes
+// the earliest muted is shown again.
+func (gs *GameState) Mute(by, sender string, muted bool) error {
+    if gs.Mutes == nil {
+        gs.Mutes = make(map[string][]string)
+    }
+    mutes := without(gs.Mutes[by], sender)
+    if muted {
+        found := false
+        for _, m := range gs.Chat {
+            found = found || m.Sender == sender
+        }
+        if !found {
+            return errors.New("Nobody in the chat sent that")
+        }
+        mutes = append(mutes, sender)
+        if len(mutes) > maxMutes {
+            mutes = mutes[len(mutes) - maxMutes:]
+        }
+    }
+    if len(mutes) == 0 {
+        delete(gs.Mutes, by)
+    } else {
+        gs.Mutes[by] = mutes
+    }
+    return nil
+}
+
+// chatFor is the chat as id sees it: nobody they have muted, and spectators'
+// messages only if they are a spectator themselves.
+func (gs *GameState) chatFor(id string) []ChatMessage {
+    spectator := !gs.InGame(id)
+    muted := gs.Mutes[id]
+    messages := make([]ChatMessage, 0)
+    for _, m := range gs.Chat {
+        if (m.Spectator && !spectator) || has(muted, m.Sender) {
+            continue
+        }
+        messages = append(messages, m.ChatMessage)
+    }
+    return messages
+}
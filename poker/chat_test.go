@@ -0,0 +1,95 @@
+package poker
+
+import (
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestChat(t *testing.T) {
+    gs, err := NewGame(0, StandardDeck, SeededSource(12))
+    if err != nil {
+        t.Fatal(err)
+    }
+    now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+    gs.clock = func() time.Time { return now }
+    gs.Sit("a", "Alice", "", "")
+    gs.Sit("b", "Bob", "", "")
+    if err := gs.Say("a", "ignored", "hello"); err != nil {
+        t.Fatal(err)
+    }
+    if err := gs.Say("a", "", strings.Repeat("x", MaxChatLength + 1)); err == nil {
+        t.Errorf("sent an overlong message")
+    }
+    if err := gs.Emote("b", "", "gg"); err != nil {
+        t.Fatal(err)
+    }
+    if err := gs.Emote("b", "", "rude"); err == nil {
+        t.Errorf("sent an unknown emote")
+    }
+    if err := gs.Say("s", "Sam", "go all in"); err != nil {
+        t.Fatal(err)
+    }
+    if chat := gs.ClientState("b").Chat; len(chat) != 2 || chat[0].Name != "Alice" || !chat[1].Emote {
+        t.Errorf("player saw %+v", chat)
+    }
+    chat := gs.ClientState("s").Chat
+    if len(chat) != 3 || !chat[2].Spectator || chat[2].Name != "Sam" {
+        t.Errorf("spectator saw %+v", chat)
+    }
+    if chat[0].Sender == "" || chat[0].Sender == "a" || chat[0].Sender == chat[2].Sender {
+        t.Errorf("senders %q and %q", chat[0].Sender, chat[2].Sender)
+    }
+    if err := gs.Mute("b", "0123456789ab", true); err == nil {
+        t.Errorf("muted a sender not in the chat")
+    }
+    if err := gs.Mute("b", chat[0].Sender, true); err != nil {
+        t.Fatal(err)
+    }
+    if chat := gs.ClientState("b").Chat; len(chat) != 1 {
+        t.Errorf("muted sender still shown: %+v", chat)
+    }
+    gs.Mute("b", chat[0].Sender, false)
+    if chat := gs.ClientState("b").Chat; len(chat) != 2 || len(gs.Mutes) != 0 {
+        t.Errorf("unmuted sender not shown: %+v", chat)
+    }
+    other, err := NewGame(0, StandardDeck, SeededSource(13))
+    if err != nil {
+        t.Fatal(err)
+    }
+    other.Say("a", "", "hello")
+    if other.ClientState("s").Chat[0].Sender == chat[0].Sender {
+        t.Errorf("sender %q is the same in another game", chat[0].Sender)
+    }
+}
+
+func TestChatRateLimit(t *testing.T) {
+    gs, err := NewGame(0, StandardDeck, SeededSource(13))
+    if err != nil {
+        t.Fatal(err)
+    }
+    now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+    gs.clock = func() time.Time { return now }
+    for i := 0; i < ChatBurst; i++ {
+        if err := gs.Say("a", "A", "spam"); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := gs.Say("a", "A", "spam"); err == nil {
+        t.Errorf("no limit on messages")
+    }
+    if err := gs.Say("b", "B", "hi"); err != nil {
+        t.Errorf("one player's limit stopped another: %v", err)
+    }
+    now = now.Add(ChatWindow)
+    if err := gs.Say("a", "A", "spam"); err != nil {
+        t.Errorf("still limited after the window: %v", err)
+    }
+    for i := 0; i < chatHistory; i++ {
+        now = now.Add(ChatWindow)
+        gs.Say("a", "A", "more")
+    }
+    if len(gs.Chat) != chatHistory {
+        t.Errorf("kept %d messages", len(gs.Chat))
+    }
+}
@@ -2,6 +2,7 @@ package poker
 
 type ChineseHand struct {
     Back, Middle, Front *Hand
+    Low Rank  // lowest rank in the deck, see DeckSpec
 }
 
 func (ch *ChineseHand) Count() int {
@@ -19,59 +20,118 @@ func (ch *ChineseHand) Fix() {
 }
 
 func (ch *ChineseHand) Royalties() int {
-    r := 0
     if (ch.Count() == 13 && ch.Fault()) {
         return 0;
     }
-    if (ch.Back != nil) {
-        switch (ch.Back.Royalty.Rank) {
-            case AceLowStraight:
-                r += 2
-            case Straight:
-                r += 2
-            case Flush:
-                r += 4
-            case FullHouse:
-                r += 6
-            case Quads:
-                r += 8
-            case AceLowStraightFlush:
-                r += 10
-            case StraightFlush:
-                r += 10
-            case Royal:
-                r += 20
-        }
+    return rowRoyalty(Back, ch.Back) + rowRoyalty(Middle, ch.Middle) + rowRoyalty(Front, ch.Front)
+}
+
+func (ch *ChineseHand) Row(pos int) *Hand {
+    switch (pos) {
+        case Back:
+            return ch.Back
+        case Middle:
+            return ch.Middle
+        case Front:
+            return ch.Front
     }
-    if (ch.Middle != nil) {
-        switch (ch.Middle.Royalty.Rank) {
-            case AceLowStraight:
-                r += 4
-            case Straight:
-                r += 4
-            case Flush:
-                r += 8
-            case FullHouse:
-                r += 12
-            case Quads:
-                r += 16
-            case AceLowStraightFlush:
-                r += 20
-            case StraightFlush:
-                r += 20
-            case Royal:
-                r += 40
-        }
+    return nil
+}
+
+func (ch *ChineseHand) setRow(pos int, h *Hand) {
+    switch (pos) {
+        case Back:
+            ch.Back = h
+        case Middle:
+            ch.Middle = h
+        case Front:
+            ch.Front = h
     }
-    if (ch.Front != nil) {
-        switch (ch.Front.Royalty.Rank) {
-            case Pair:
-                if cr := int(ch.Front.Royalty.Cards[0].Rank()) - 3; cr > 0 {
-                    r += cr;
-                }
-            case Trips:
-                r += int(ch.Front.Royalty.Cards[0].Rank()) + 10;
-        }
+}
+
+// Place adds c to the row at pos, if the row has room for it.
+func (ch *ChineseHand) Place(pos int, c Card) error {
+    if pos < Back || pos > Front || ch.Row(pos).Count() >= RowSize(pos) {
+        return ErrInvalidPlay
+    }
+    if row := ch.Row(pos); row != nil {
+        ch.setRow(pos, row.Add(c))
+    } else {
+        ch.setRow(pos, newHand([]Card{c}, ch.Low))
+    }
+    return nil
+}
+
+func (ch *ChineseHand) Cards() []Card {
+    cards := ch.Back.Cards()
+    cards = append(cards, ch.Middle.Cards()...)
+    return append(cards, ch.Front.Cards()...)
+}
+
+func RowSize(pos int) int {
+    if pos == Front {
+        return 3
+    }
+    return 5
+}
+
+func rowRoyalty(pos int, h *Hand) int {
+    if h == nil {
+        return 0
+    }
+    r := 0
+    switch (pos) {
+        case Back:
+            switch (h.Royalty.Rank) {
+                case AceLowStraight:
+                    r += 2
+                case Straight:
+                    r += 2
+                case Flush:
+                    r += 4
+                case FullHouse:
+                    r += 6
+                case Quads:
+                    r += 8
+                case AceLowStraightFlush:
+                    r += 10
+                case StraightFlush:
+                    r += 10
+                case Royal:
+                    r += 20
+                case FiveOfAKind:
+                    r += 20
+            }
+        case Middle:
+            switch (h.Royalty.Rank) {
+                case AceLowStraight:
+                    r += 4
+                case Straight:
+                    r += 4
+                case Flush:
+                    r += 8
+                case FullHouse:
+                    r += 12
+                case Quads:
+                    r += 16
+                case AceLowStraightFlush:
+                    r += 20
+                case StraightFlush:
+                    r += 20
+                case Royal:
+                    r += 40
+                case FiveOfAKind:
+                    r += 40
+            }
+        case Front:
+            switch (h.Royalty.Rank) {
+                case Pair:
+                    if cr := int(h.Royalty.Cards[0].Rank()) - 3; cr > 0 {
+                        r += cr;
+                    }
+                case Trips:
+                    r += int(h.Royalty.Cards[0].Rank()) + 10;
+            }
     }
     return r
 }
@@ -79,7 +139,7 @@ func (ch *ChineseHand) Royalties() int {
 func Faults(hands []*ChineseHand) []bool {
     result := make([]bool, 0)
     for _, hand := range hands {
-        result = append(result, hand.Fault())
+        result = append(result, hand != nil && hand.Fault())
     }
     return result
 }
@@ -108,12 +168,23 @@ func getWinners(hands []*Hand, faults []bool) []int {
 func selectHands(hands []*ChineseHand, s func(*ChineseHand)*Hand) []*Hand {
     result := make([]*Hand, 0)
     for _, hand := range hands {
+        if hand == nil {
+            result = append(result, nil)
+            continue
+        }
         result = append(result, s(hand))
     }
     return result
 }
 
+// Winners returns the best hands in each row, leaving out fouled hands and
+// seats with no hand.
 func Winners(hands []*ChineseHand, faults []bool) (b, m, f []int) {
+    out := make([]bool, len(hands))
+    for i, hand := range hands {
+        out[i] = hand == nil || (faults != nil && faults[i])
+    }
+    faults = out
     return getWinners(selectHands(hands, func(h *ChineseHand)*Hand{return h.Back}), faults),
         getWinners(selectHands(hands, func(h *ChineseHand)*Hand{return h.Middle}), faults),
         getWinners(selectHands(hands, func(h *ChineseHand)*Hand{return h.Front}), faults)
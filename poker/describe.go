@@ -0,0 +1,180 @@
+package poker
+
+import (
+    "fmt"
+    "strings"
+)
+
+var rankNames = []string{"Two", "Three", "Four", "Five", "Six", "Seven", "Eight",
+    "Nine", "Ten", "Jack", "Queen", "King", "Ace"}
+
+// Name returns the rank in words, e.g. "Queen".
+func (r Rank) Name() string {
+    return rankNames[r]
+}
+
+// Plural returns the rank in words for more than one card, e.g. "Sixes".
+func (r Rank) Plural() string {
+    if r == 4 {
+        return "Sixes"
+    }
+    return rankNames[r] + "s"
+}
+
+// keyRanks returns the ranks Compare weighs for h, most significant first,
+// along with what each one is called.
+func (h *Hand) keyRanks() ([]Rank, []string) {
+    cards := h.Royalty.Cards
+    top := cards[len(cards)-1].Rank()
+    kickers := make([]Rank, 0, len(h.Kickers))
+    for i := len(h.Kickers) - 1; i >= 0; i-- {
+        kickers = append(kickers, h.Kickers[i].Rank())
+    }
+    kickerNames := []string{"first kicker", "second kicker", "third kicker", "fourth kicker"}
+    if len(kickers) == 1 {
+        kickerNames = []string{"kicker"}
+    }
+    switch h.Royalty.Rank {
+        case HighCard, Flush:
+            ranks := make([]Rank, 0, len(cards) + len(kickers))
+            for i := len(cards) - 1; i >= 0; i-- {
+                ranks = append(ranks, cards[i].Rank())
+            }
+            ranks = append(ranks, kickers...)
+            return ranks, []string{"top card", "second card", "third card", "fourth card", "fifth card"}
+        case Pair, Trips, Quads:
+            name := map[int]string{Pair: "pair", Trips: "trips", Quads: "quads"}[h.Royalty.Rank]
+            return append([]Rank{top}, kickers...), append([]string{name}, kickerNames...)
+        case TwoPair:
+            return append([]Rank{top, cards[0].Rank()}, kickers...), append([]string{"top pair", "bottom pair"}, kickerNames...)
+        case FullHouse:
+            // The pair comes first, the trips last.
+            return []Rank{top, cards[0].Rank()}, []string{"trips", "pair"}
+        case AceLowStraight, AceLowStraightFlush:
+            return []Rank{cards[3].Rank()}, []string{"top card"}
+    }
+    return []Rank{top}, []string{"top card"}
+}
+
+var categoryNames = map[int]string{
+    HighCard: "high card",
+    Pair: "a pair",
+    TwoPair: "two pair",
+    Trips: "trips",
+    AceLowStraight: "a straight",
+    Straight: "a straight",
+    Flush: "a flush",
+    FullHouse: "a full house",
+    Quads: "quads",
+    AceLowStraightFlush: "a straight flush",
+    StraightFlush: "a straight flush",
+    Royal: "a royal flush",
+    FiveOfAKind: "five of a kind",
+}
+
+// Describe names the hand the way players say it, e.g. "Kings full of Fours"
+// or "Two pair, Aces and Sevens, Jack kicker".
+func (h *Hand) Describe() string {
+    if h == nil {
+        return "No hand"
+    }
+    ranks, _ := h.keyRanks()
+    switch h.Royalty.Rank {
+        case HighCard:
+            return ranks[0].Name() + " high"
+        case Pair:
+            return "Pair of " + ranks[0].Plural()
+        case TwoPair:
+            s := fmt.Sprintf("Two pair, %s and %s", ranks[0].Plural(), ranks[1].Plural())
+            if len(ranks) > 2 {
+                s += ", " + ranks[2].Name() + " kicker"
+            }
+            return s
+        case Trips:
+            return "Trip " + ranks[0].Plural()
+        case AceLowStraight, Straight:
+            return ranks[0].Name() + "-high straight"
+        case Flush:
+            return ranks[0].Name() + "-high flush"
+        case FullHouse:
+            return ranks[0].Plural() + " full of " + ranks[1].Plural()
+        case Quads:
+            return "Four " + ranks[0].Plural()
+        case AceLowStraightFlush, StraightFlush:
+            return ranks[0].Name() + "-high straight flush"
+        case Royal:
+            return "Royal flush"
+        case FiveOfAKind:
+            return "Five " + ranks[0].Plural()
+    }
+    return h.Royalty.String()
+}
+
+var countWords = []string{"", "", "two", "three", "four"}
+
+// CompareExplain says why the better of a and b wins, e.g. "a flush beats a
+// straight" or "same pair, higher second kicker", or "identical hands" if
+// they tie.
+func CompareExplain(a, b *Hand) string {
+    d := a.Compare(b)
+    if d == 0 {
+        return "identical hands"
+    }
+    if d < 0 {
+        a, b = b, a
+    }
+    if b == nil {
+        return "no hand to beat"
+    }
+    if a.category() != b.category() {
+        return categoryNames[a.Royalty.Rank] + " beats " + categoryNames[b.Royalty.Rank]
+    }
+    ar, names := a.keyRanks()
+    br, _ := b.keyRanks()
+    for k := 0; k < len(ar) && k < len(br); k++ {
+        if ar[k] == br[k] {
+            continue
+        }
+        higher := "higher " + names[k]
+        switch {
+            case k == 0:
+                return higher
+            case a.Royalty.Rank == HighCard || a.Royalty.Rank == Flush:
+                if k == 1 {
+                    return "same top card, " + higher
+                }
+                return "same top " + countWords[k] + " cards, " + higher
+            case a.Royalty.Rank == TwoPair && k == 2:
+                return "same two pair, " + higher
+        }
+        return "same " + names[0] + ", " + higher
+    }
+    return "more cards"
+}
+
+// explainRow describes how the best hands in a row beat the best of the
+// rest that did not foul, for the showdown.
+func explainRow(hands []*Hand, winners []int, faults []bool) string {
+    if len(winners) == 0 {
+        return ""
+    }
+    won := make(map[int]bool)
+    names := make([]string, 0, len(winners))
+    for _, i := range winners {
+        won[i] = true
+        names = append(names, hands[i].Describe())
+    }
+    var runnerUp *Hand
+    for i, h := range hands {
+        if h != nil && !won[i] && !(faults != nil && faults[i]) && (runnerUp == nil || h.Compare(runnerUp) > 0) {
+            runnerUp = h
+        }
+    }
+    if runnerUp == nil {
+        if len(names) == 1 {
+            return names[0]
+        }
+        return strings.Join(names, ", ") + " split"
+    }
+    return fmt.Sprintf("%s beats %s: %s", names[0], runnerUp.Describe(), CompareExplain(hands[winners[0]], runnerUp))
+}
@@ -0,0 +1,58 @@
+package poker
+
+import (
+    "testing"
+)
+
+func handOf(t *testing.T, s string) *Hand {
+    cards, err := ParseCards(s)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return NewHand(cards)
+}
+
+func TestDescribe(t *testing.T) {
+    tests := []struct {
+        cards, want string
+    }{
+        {"KsKhKd4s4h", "Kings full of Fours"},
+        {"QsJhTd9c8s", "Queen-high straight"},
+        {"As2h3d4c5s", "Five-high straight"},
+        {"AsAh7d7cJs", "Two pair, Aces and Sevens, Jack kicker"},
+        {"9s9h9d2c3s", "Trip Nines"},
+        {"6s6h2d4c3s", "Pair of Sixes"},
+        {"As9h7d5c3s", "Ace high"},
+        {"Ks9s7s5s3s", "King-high flush"},
+        {"2s2h2d2c3s", "Four Twos"},
+        {"AsKsQsJsTs", "Royal flush"},
+        {"6s6h", "Pair of Sixes"},
+    }
+    for _, test := range tests {
+        if got := handOf(t, test.cards).Describe(); got != test.want {
+            t.Errorf("%s: got %q, want %q", test.cards, got, test.want)
+        }
+    }
+}
+
+func TestCompareExplain(t *testing.T) {
+    tests := []struct {
+        a, b, want string
+    }{
+        {"6s6hAd9c3s", "6d6cAh8c4s", "same pair, higher second kicker"},
+        {"6s6hAd9c3s", "As9s7s5s3s", "a flush beats a pair"},
+        {"AsAh7d7cJs", "AdAc7h7sTs", "same two pair, higher kicker"},
+        {"AsAh7d7cJs", "AdAc6h6sTs", "same top pair, higher bottom pair"},
+        {"QsQhQdAsAh", "KsKhKd2s2h", "higher trips"},
+        {"As9h7d5c3s", "Ad9c7h5s2s", "same top four cards, higher fifth card"},
+        {"As9h7d5c3s", "Ad9c7h5s3d", "identical hands"},
+    }
+    for _, test := range tests {
+        a, b := handOf(t, test.a), handOf(t, test.b)
+        for _, got := range []string{CompareExplain(a, b), CompareExplain(b, a)} {
+            if got != test.want {
+                t.Errorf("%s vs %s: got %q, want %q", test.a, test.b, got, test.want)
+            }
+        }
+    }
+}
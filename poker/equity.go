@@ -0,0 +1,322 @@
+package poker
+
+import (
+    "errors"
+    "fmt"
+    "math/rand"
+    "runtime"
+    "sync"
+)
+
+const (
+    Holdem = iota
+    Omaha
+    OmahaHiLo
+)
+
+const (
+    equityExactLimit = 50000
+    EquityTrials = 20000
+)
+
+func HoleCards(game int) int {
+    if game == Omaha || game == OmahaHiLo {
+        return 4
+    }
+    return 2
+}
+
+// BestHand returns the strongest five card hand that can be made from cards.
+func BestHand(cards []Card) *Hand {
+    return StandardDeck.BestHand(cards)
+}
+
+func (ds DeckSpec) BestHand(cards []Card) *Hand {
+    if len(cards) <= 5 {
+        return ds.NewHand(append([]Card{}, cards...))
+    }
+    var best *Hand
+    combinations(cards, 5, func(chosen, _ []Card) {
+        if h := ds.NewHand(append([]Card{}, chosen...)); h.Compare(best) > 0 {
+            best = h
+        }
+    })
+    return best
+}
+
+// OmahaHand returns the strongest hand using exactly two hole cards and three
+// board cards.
+func OmahaHand(hole, board []Card) *Hand {
+    return StandardDeck.OmahaHand(hole, board)
+}
+
+func (ds DeckSpec) OmahaHand(hole, board []Card) *Hand {
+    var best *Hand
+    combinations(hole, 2, func(h, _ []Card) {
+        combinations(board, 3, func(b, _ []Card) {
+            cards := append(append([]Card{}, h...), b...)
+            if hand := ds.NewHand(cards); hand.Compare(best) > 0 {
+                best = hand
+            }
+        })
+    })
+    return best
+}
+
+func showdownHand(game int, spec DeckSpec, hole, board []Card) *Hand {
+    if game == Omaha || game == OmahaHiLo {
+        return spec.OmahaHand(hole, board)
+    }
+    return spec.BestHand(append(append([]Card{}, hole...), board...))
+}
+
+// EquityResult holds, for each set of hole cards, the percentage of boards
+// on which it takes the whole pot, part of it or none of it, and its overall
+// share of the pot.
+type EquityResult struct {
+    Win, Tie, Loss []float64
+    Equity []float64
+    Trials int
+    Exact bool
+}
+
+type tally struct {
+    win, tie, loss, share []float64
+    weight float64
+    n int
+}
+
+func newTally(players int) *tally {
+    return &tally{
+        make([]float64, players),
+        make([]float64, players),
+        make([]float64, players),
+        make([]float64, players),
+        0, 0}
+}
+
+func (t *tally) add(other *tally) {
+    for i, _ := range t.win {
+        t.win[i] += other.win[i]
+        t.tie[i] += other.tie[i]
+        t.loss[i] += other.loss[i]
+        t.share[i] += other.share[i]
+    }
+    t.weight += other.weight
+    t.n += other.n
+}
+
+// deal is one complete set of hole cards and board to be shown down.
+type deal struct {
+    holes [][]Card
+    board []Card
+    weight float64
+}
+
+// shares splits the pot for one deal.  In Omaha Hi/Lo half goes to the best
+// eight-or-better low, if there is one.
+func shares(game int, spec DeckSpec, d *deal) []float64 {
+    result := make([]float64, len(d.holes))
+    hands := make([]*Hand, len(d.holes))
+    for i, hole := range d.holes {
+        hands[i] = showdownHand(game, spec, hole, d.board)
+    }
+    pot := 1.0
+    if game == OmahaHiLo {
+        var best *LowHand
+        lows := make([]*LowHand, len(d.holes))
+        for i, hole := range d.holes {
+            lows[i] = OmahaLow(hole, d.board)
+            if lows[i].Compare(best) > 0 {
+                best = lows[i]
+            }
+        }
+        if best != nil {
+            pot = 0.5
+            winners := make([]int, 0)
+            for i, low := range lows {
+                if low.Compare(best) == 0 {
+                    winners = append(winners, i)
+                }
+            }
+            for _, i := range winners {
+                result[i] += pot / float64(len(winners))
+            }
+        }
+    }
+    winners := getWinners(hands, nil)
+    for _, i := range winners {
+        result[i] += pot / float64(len(winners))
+    }
+    return result
+}
+
+func (t *tally) showdown(game int, spec DeckSpec, d *deal) {
+    for i, share := range shares(game, spec, d) {
+        switch {
+            case share == 1:
+                t.win[i] += d.weight
+            case share > 0:
+                t.tie[i] += d.weight
+            default:
+                t.loss[i] += d.weight
+        }
+        t.share[i] += d.weight * share
+    }
+    t.weight += d.weight
+    t.n++
+}
+
+func (t *tally) result(exact bool) *EquityResult {
+    er := &EquityResult{Trials: t.n, Exact: exact}
+    pct := func(xs []float64) []float64 {
+        result := make([]float64, len(xs))
+        for i, x := range xs {
+            if t.weight > 0 {
+                result[i] = 100 * x / t.weight
+            }
+        }
+        return result
+    }
+    er.Win, er.Tie, er.Loss, er.Equity = pct(t.win), pct(t.tie), pct(t.loss), pct(t.share)
+    return er
+}
+
+// equityChunks is how many pieces a simulation is cut into.  Each piece
+// draws from its own generator seeded from the caller's RandSource, so the
+// results depend only on that source and not on how many CPUs share the work.
+const equityChunks = 16
+
+// run shows down every deal in exact or, if exact is nil, trials deals drawn
+// from sample, splitting the work across one goroutine per CPU.  sample may
+// return nil to reject a draw; rejected draws are retried, up to a limit.
+func run(game int, spec DeckSpec, players int, exact []*deal, trials int, src RandSource, sample func(*rand.Rand) *deal) (*EquityResult, error) {
+    chunks := equityChunks
+    if exact != nil && len(exact) < chunks {
+        chunks = len(exact)
+    }
+    parts := make([]*tally, chunks)
+    rnds := make([]*rand.Rand, chunks)
+    for c := 0; c < chunks; c++ {
+        parts[c] = newTally(players)
+        if exact == nil {
+            rnd, err := newRand(src)
+            if err != nil {
+                return nil, err
+            }
+            rnds[c] = rnd
+        }
+    }
+    work := make(chan int, chunks)
+    for c := 0; c < chunks; c++ {
+        work <- c
+    }
+    close(work)
+    var wg sync.WaitGroup
+    for w := 0; w < runtime.NumCPU(); w++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            for c := range work {
+                t := parts[c]
+                if exact != nil {
+                    for _, d := range exact[c * len(exact) / chunks:(c + 1) * len(exact) / chunks] {
+                        t.showdown(game, spec, d)
+                    }
+                    continue
+                }
+                n := (c + 1) * trials / chunks - c * trials / chunks
+                for tries := 0; t.n < n && tries < 100 * n; tries++ {
+                    if d := sample(rnds[c]); d != nil {
+                        t.showdown(game, spec, d)
+                    }
+                }
+            }
+        }()
+    }
+    wg.Wait()
+    total := newTally(players)
+    for _, part := range parts {
+        total.add(part)
+    }
+    return total.result(exact != nil), nil
+}
+
+// completeBoard fills board up to five cards from the front of deck after
+// shuffling just enough of it.
+func completeBoard(rnd *rand.Rand, board, deck []Card) []Card {
+    b := append(make([]Card, 0, 5), board...)
+    for j := 0; len(b) < 5; j++ {
+        k := j + rnd.Intn(len(deck) - j)
+        deck[j], deck[k] = deck[k], deck[j]
+        b = append(b, deck[j])
+    }
+    return b
+}
+
+// Equity computes how often each set of hole cards wins against the others
+// once the board is completed from the deck described by spec.  Every
+// possible board is dealt when there are at most equityExactLimit of them;
+// otherwise trials random boards are drawn using src.
+func Equity(game int, spec DeckSpec, holes [][]Card, board, dead []Card, trials int, src RandSource) (*EquityResult, error) {
+    if game != Holdem && game != Omaha && game != OmahaHiLo {
+        return nil, errors.New("Unknown game")
+    }
+    if game == OmahaHiLo && (spec.Short() || spec.Jokers > 0) {
+        return nil, errors.New("Omaha Hi/Lo needs a full deck without jokers")
+    }
+    if len(holes) < 2 {
+        return nil, errors.New("Need at least two hands")
+    }
+    if len(board) > 5 {
+        return nil, errors.New("Board has more than five cards")
+    }
+    used := append(append([]Card{}, board...), dead...)
+    for i, hole := range holes {
+        if len(hole) != HoleCards(game) {
+            return nil, fmt.Errorf("Hand %d needs %d hole cards", i + 1, HoleCards(game))
+        }
+        used = append(used, hole...)
+    }
+    if err := spec.check(used); err != nil {
+        return nil, err
+    }
+    live := remaining(spec.Ordered(), used)
+    need := 5 - len(board)
+    if choose(len(live), need) <= equityExactLimit {
+        deals := make([]*deal, 0)
+        combinations(live, need, func(chosen, _ []Card) {
+            b := append(append([]Card{}, board...), chosen...)
+            deals = append(deals, &deal{holes, b, 1})
+        })
+        return run(game, spec, len(holes), deals, 0, nil, nil)
+    }
+    return run(game, spec, len(holes), nil, trials, src, func(rnd *rand.Rand) *deal {
+        // Copy the deck so that workers can shuffle it concurrently.
+        return &deal{holes, completeBoard(rnd, board, append([]Card{}, live...)), 1}
+    })
+}
+
+// remaining returns the cards of deck that are not in used.
+func remaining(deck Deck, used []Card) []Card {
+    if dead, ok := NewCardSet(used); ok {
+        live := make([]Card, 0, len(deck))
+        for _, card := range deck {
+            if !dead.Contains(card) {
+                live = append(live, card)
+            }
+        }
+        return live
+    }
+    dead := make(map[Card]bool)
+    for _, card := range used {
+        dead[card] = true
+    }
+    live := make([]Card, 0)
+    for _, card := range deck {
+        if !dead[card] {
+            live = append(live, card)
+        }
+    }
+    return live
+}
@@ -0,0 +1,114 @@
+package poker
+
+import (
+    "math"
+    "testing"
+)
+
+func TestParseRank(t *testing.T) {
+    tests := []struct {
+        in byte
+        want Rank
+        ok bool
+    }{
+        {'2', 0, true}, {'9', 7, true}, {'T', 8, true}, {'t', 8, true},
+        {'J', 9, true}, {'q', 10, true}, {'K', 11, true}, {'a', 12, true},
+        // Letters are only ranks in their own right, never shifted digits.
+        {'R', 0, false}, {'S', 0, false}, {'U', 0, false}, {'Y', 0, false},
+        {'1', 0, false}, {'0', 0, false},
+    }
+    for _, test := range tests {
+        r, err := ParseRank(test.in)
+        if (err == nil) != test.ok || (test.ok && r != test.want) {
+            t.Errorf("ParseRank(%c) = %v, %v", test.in, r, err)
+        }
+    }
+}
+
+func TestParseCards(t *testing.T) {
+    tests := []struct {
+        in string
+        want []Card
+        ok bool
+    }{
+        {"As", []Card{NewCard(12, 0)}, true},
+        {"Ts 9h", []Card{NewCard(8, 0), NewCard(7, 2)}, true},
+        {"10d,2c", []Card{NewCard(8, 3), NewCard(0, 1)}, true},
+        {"AsKd\nX1", []Card{NewCard(12, 0), NewCard(11, 3), Joker(0)}, true},
+        {"", []Card{}, true},
+        {"Ax", nil, false},
+        {"1s", nil, false},
+        {"As K", nil, false},
+    }
+    for _, test := range tests {
+        cards, err := ParseCards(test.in)
+        if (err == nil) != test.ok {
+            t.Errorf("ParseCards(%q) error %v", test.in, err)
+            continue
+        }
+        if len(cards) != len(test.want) {
+            t.Errorf("ParseCards(%q) = %v, want %v", test.in, cards, test.want)
+            continue
+        }
+        for i := range cards {
+            if cards[i] != test.want[i] {
+                t.Errorf("ParseCards(%q) = %v, want %v", test.in, cards, test.want)
+                break
+            }
+        }
+    }
+}
+
+func cardsOf(t *testing.T, s string) []Card {
+    cards, err := ParseCards(s)
+    if err != nil {
+        t.Fatal(err)
+    }
+    return cards
+}
+
+func TestEquityExact(t *testing.T) {
+    // On the river there is nothing left to deal.
+    holes := [][]Card{cardsOf(t, "AsAh"), cardsOf(t, "KsKh")}
+    er, err := Equity(Holdem, StandardDeck, holes, cardsOf(t, "2c7d9hJcQd"), nil, EquityTrials, SeededSource(1))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !er.Exact || er.Trials != 1 || er.Win[0] != 100 || er.Loss[1] != 100 {
+        t.Errorf("river equity %+v", er)
+    }
+    // With a turn to come, the kings need one of the two kings left in 44.
+    er, _ = Equity(Holdem, StandardDeck, holes, cardsOf(t, "2c7d9hJc"), nil, EquityTrials, SeededSource(1))
+    if !er.Exact || er.Trials != 44 || math.Abs(er.Equity[1] - 100 * 2.0 / 44) > 1e-9 {
+        t.Errorf("turn equity %+v", er)
+    }
+}
+
+func TestEquitySampled(t *testing.T) {
+    holes := [][]Card{cardsOf(t, "AsAh"), cardsOf(t, "KsKh")}
+    first, err := Equity(Holdem, StandardDeck, holes, nil, nil, 4000, SeededSource(2))
+    if err != nil {
+        t.Fatal(err)
+    }
+    // Aces hold up about 82% of the time.
+    if first.Exact || first.Trials != 4000 || first.Equity[0] < 78 || first.Equity[0] > 86 {
+        t.Errorf("preflop equity %+v", first)
+    }
+    // The work is shared between goroutines, each with its own generator, so
+    // the seed alone decides the result.
+    again, _ := Equity(Holdem, StandardDeck, holes, nil, nil, 4000, SeededSource(2))
+    if again.Equity[0] != first.Equity[0] {
+        t.Errorf("same seed gave %v then %v", first.Equity, again.Equity)
+    }
+}
+
+func TestEquityErrors(t *testing.T) {
+    aces := [][]Card{cardsOf(t, "AsAh"), cardsOf(t, "AsKh")}
+    if _, err := Equity(Holdem, StandardDeck, aces, nil, nil, 100, SeededSource(1)); err == nil {
+        t.Error("dealt the ace of spades twice")
+    }
+    short := [][]Card{cardsOf(t, "AsAh"), cardsOf(t, "KsKh")}
+    if _, err := Equity(Omaha, StandardDeck, short, nil, nil, 100, SeededSource(1)); err == nil {
+        t.Error("played Omaha with two hole cards")
+    }
+}
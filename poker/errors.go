@@ -0,0 +1,24 @@
+package poker
+
+import (
+    "errors"
+)
+
+// Errors a player can run into by acting out of turn or against the table's
+// rules, for callers that need to tell them apart.
+var (
+    ErrStarted = errors.New("Game has already started")
+    ErrNotInProgress = errors.New("Game is not in progress")
+    ErrNotYourTurn = errors.New("It is not your turn!")
+    ErrInvalidPlay = errors.New("Invalid play")
+    ErrNoPlayers = errors.New("No one is playing")
+    ErrMatchOver = errors.New("Match is over")
+    ErrNotInGame = errors.New("You are not in this game")
+    ErrAlreadySitting = errors.New("You are already sitting")
+    ErrHandInProgress = errors.New("Wait for the hand to finish")
+    ErrLocked = errors.New("Table is locked")
+    ErrNeedsCode = errors.New("This table needs an invite code")
+    ErrFull = errors.New("Game is full")
+    ErrNotOwner = errors.New("Only the table's owner can do that")
+    ErrNoSeed = errors.New("Waiting for every player's shuffle seed")
+)
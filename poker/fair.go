@@ -0,0 +1,104 @@
+package poker
+
+import (
+    "crypto/hmac"
+    "crypto/sha256"
+    "encoding/binary"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// Fairness is what a player needs to check that a hand was dealt honestly.
+// The server commits to a secret seed before anyone acts, each player adds a
+// seed of their own, and the deck order follows from all of them together.
+// ServerSeed and Dealt are filled in only once the hand is over.
+type Fairness struct {
+    Spec DeckSpec
+    Commitment string
+    ClientSeeds []string
+    ServerSeed string
+    Dealt []Card
+}
+
+func NewServerSeed(src RandSource) (string, error) {
+    b := make([]byte, 32)
+    if _, err := src.Read(b); err != nil {
+        return "", err
+    }
+    return hex.EncodeToString(b), nil
+}
+
+// Commitment is the hex SHA-256 of the server seed, published before the deal.
+func Commitment(serverSeed string) string {
+    sum := sha256.Sum256([]byte(serverSeed))
+    return hex.EncodeToString(sum[:])
+}
+
+// seedStream yields the bytes of HMAC-SHA256(serverSeed, message + ":" + n)
+// for n = 0, 1, 2, ..., where message is the client seeds joined with ":".
+type seedStream struct {
+    key []byte
+    message string
+    block []byte
+    n int
+}
+
+func (s *seedStream) uint32() uint32 {
+    if len(s.block) < 4 {
+        mac := hmac.New(sha256.New, s.key)
+        mac.Write([]byte(s.message + ":" + strconv.Itoa(s.n)))
+        s.block = mac.Sum(nil)
+        s.n++
+    }
+    v := binary.BigEndian.Uint32(s.block)
+    s.block = s.block[4:]
+    return v
+}
+
+// intn returns a uniform number in [0, n), discarding values that would bias
+// the result.
+func (s *seedStream) intn(n int) int {
+    limit := uint32((1 << 32) / uint64(n) * uint64(n) - 1)
+    for {
+        if v := s.uint32(); v <= limit {
+            return int(v % uint32(n))
+        }
+    }
+}
+
+// SeededShuffle orders the deck by a Fisher-Yates shuffle driven by the
+// seeds, so anyone holding them can recompute it.
+func (ds DeckSpec) SeededShuffle(serverSeed string, clientSeeds []string) Deck {
+    s := &seedStream{key: []byte(serverSeed), message: strings.Join(clientSeeds, ":")}
+    d := ds.Ordered()
+    for i, _ := range d {
+        j := i + s.intn(len(d) - i)
+        d[i], d[j] = d[j], d[i]
+    }
+    return d
+}
+
+// Verify checks that the revealed server seed matches its commitment and that
+// the cards dealt are the top of the deck the seeds produce, in order.  With
+// several decks only the faces are compared, since copies share a code.
+func (f *Fairness) Verify() error {
+    if f.ServerSeed == "" {
+        return errors.New("The server seed has not been revealed")
+    }
+    if Commitment(f.ServerSeed) != strings.ToLower(f.Commitment) {
+        return errors.New("The server seed does not match the commitment")
+    }
+    d := f.Spec.SeededShuffle(f.ServerSeed, f.ClientSeeds)
+    if len(f.Dealt) > len(d) {
+        return errors.New("More cards were dealt than the deck holds")
+    }
+    for i, card := range f.Dealt {
+        if d[i].Face() != card.Face() {
+            return fmt.Errorf("Card %d should have been %s but was %s", i + 1, d[i].Code(), card.Code())
+        }
+    }
+    return nil
+}
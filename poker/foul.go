@@ -0,0 +1,311 @@
+package poker
+
+import (
+    "errors"
+)
+
+const (
+    foulSamples = 2000
+    foulExactLimit = 20000
+)
+
+// FoulOdds describes how a partially placed ChineseHand can still finish,
+// assuming the empty slots are filled uniformly at random from the live cards.
+type FoulOdds struct {
+    Fouled bool            // every completion fouls
+    Probability float64    // fraction of completions that foul
+    Exact bool             // Probability was enumerated rather than sampled
+    Trials int
+    Reachable [][]int      // royalty-paying ranks each row can still make
+}
+
+func (fo *FoulOdds) CanMake(pos, rank int) bool {
+    for _, r := range fo.Reachable[pos] {
+        if r == rank {
+            return true
+        }
+    }
+    return false
+}
+
+func withCards(h *Hand, extra []Card, low Rank) *Hand {
+    cards := append(h.Cards(), extra...)
+    if len(cards) == 0 {
+        return nil
+    }
+    return newHand(cards, low)
+}
+
+func choose(n, k int) float64 {
+    if k < 0 || k > n {
+        return 0
+    }
+    c := 1.0
+    for i := 0; i < k; i++ {
+        c = c * float64(n - i) / float64(i + 1)
+    }
+    return c
+}
+
+// combinations calls f with every k-card subset of cards and the cards left over.
+func combinations(cards []Card, k int, f func(chosen, rest []Card)) {
+    chosen := make([]Card, 0, k)
+    var rec func(start int, rest []Card)
+    rec = func(start int, rest []Card) {
+        if len(chosen) == k {
+            f(chosen, append(rest, cards[start:]...))
+            return
+        }
+        for i := start; i <= len(cards) - (k - len(chosen)); i++ {
+            chosen = append(chosen, cards[i])
+            rec(i + 1, append(rest, cards[start:i]...))
+            chosen = chosen[:len(chosen)-1]
+        }
+    }
+    rec(0, make([]Card, 0, len(cards)))
+}
+
+// reachable maps every rank the row at pos can finish as, taking the
+// remaining cards from live, to the best royalty it pays as that rank, and
+// also returns the strongest and weakest hands the row can end up with.  It
+// walks the multisets of ranks that could fill the empty slots, counting the
+// live jokers as one more rank; suits only matter for flushes, so each
+// multiset is tried once as a flush in every suit and once as a non-flush.
+func reachable(pos int, h *Hand, live []Card, low Rank) (ranks map[int]int, best, worst *Hand) {
+    placed := h.Cards()
+    empty := RowSize(pos) - len(placed)
+    ranks = make(map[int]int)
+    if empty <= 0 {
+        if h != nil {
+            ranks[h.Royalty.Rank] = rowRoyalty(pos, h)
+        }
+        return ranks, h, h
+    }
+    byRank := make([][]Card, 14)
+    for _, card := range live {
+        byRank[rankIndex(card)] = append(byRank[rankIndex(card)], card)
+    }
+    add := func(cards []Card) {
+        hand := newHand(append(append([]Card{}, placed...), cards...), low)
+        royalty := rowRoyalty(pos, hand)
+        if r, ok := ranks[hand.Royalty.Rank]; !ok || royalty > r {
+            ranks[hand.Royalty.Rank] = royalty
+        }
+        if best == nil || hand.Compare(best) > 0 {
+            best = hand
+        }
+        if worst == nil || hand.Compare(worst) < 0 {
+            worst = hand
+        }
+    }
+    counts := make([]int, 14)
+    var rec func(rank, left int)
+    rec = func(rank, left int) {
+        if left == 0 {
+            cards := make([]Card, 0, empty)
+            for r, n := range counts {
+                cards = append(cards, byRank[r][:n]...)
+            }
+            if RowSize(pos) < 5 {
+                add(cards)
+                return
+            }
+            for s := Suit(0); s < 4; s++ {
+                if flush := suited(placed, cards, byRank, s); flush != nil {
+                    add(flush)
+                }
+            }
+            if mixed := unsuited(placed, cards, byRank); mixed != nil {
+                add(mixed)
+            }
+            return
+        }
+        if rank == len(counts) {
+            return
+        }
+        for n := min(left, len(byRank[rank])); n >= 0; n-- {
+            counts[rank] = n
+            rec(rank + 1, left - n)
+        }
+        counts[rank] = 0
+    }
+    rec(0, empty)
+    return ranks, best, worst
+}
+
+// rankIndex buckets jokers after the aces.
+func rankIndex(c Card) int {
+    if c.IsJoker() {
+        return 13
+    }
+    return int(c.Rank())
+}
+
+// suited picks cards of suit s with the same ranks as cards, or returns nil
+// if the row cannot be a flush in s.  Jokers fit any suit.
+func suited(placed, cards []Card, byRank [][]Card, s Suit) []Card {
+    for _, card := range placed {
+        if !card.IsJoker() && card.Suit() != s {
+            return nil
+        }
+    }
+    result := make([]Card, 0, len(cards))
+    taken := make(map[Card]bool)
+    for _, card := range cards {
+        found := false
+        for _, other := range byRank[rankIndex(card)] {
+            if !taken[other] && (other.IsJoker() || other.Suit() == s) {
+                result = append(result, other)
+                taken[other] = true
+                found = true
+                break
+            }
+        }
+        if !found {
+            return nil
+        }
+    }
+    return result
+}
+
+// unsuited picks cards with the same ranks as cards that do not all share a
+// suit with the placed cards, or returns nil if that is impossible.
+func unsuited(placed, cards []Card, byRank [][]Card) []Card {
+    s := Suit(-1)
+    for _, card := range append(append([]Card{}, placed...), cards...) {
+        if card.IsJoker() {
+            continue
+        }
+        if s < 0 {
+            s = card.Suit()
+        } else if card.Suit() != s {
+            return cards
+        }
+    }
+    for i, card := range cards {
+        if card.IsJoker() {
+            continue
+        }
+        for _, other := range byRank[rankIndex(card)] {
+            if other.Suit() != s && !contains(cards, other) {
+                result := append([]Card{}, cards...)
+                result[i] = other
+                return result
+            }
+        }
+    }
+    return nil
+}
+
+func contains(cards []Card, c Card) bool {
+    for _, card := range cards {
+        if card == c {
+            return true
+        }
+    }
+    return false
+}
+
+// Odds reports the foul outlook for a partially placed hand.  The empty slots
+// are filled from live; when there are few enough ways to do that every
+// completion is checked, otherwise samples random completions are drawn
+// using src.
+func (ch *ChineseHand) Odds(live []Card, samples int, src RandSource) (*FoulOdds, error) {
+    empty := make([]int, 3)
+    total := 0
+    for pos := Back; pos <= Front; pos++ {
+        empty[pos] = RowSize(pos) - ch.Row(pos).Count()
+        total += empty[pos]
+    }
+    if total > len(live) {
+        return nil, errors.New("Not enough live cards to finish the hand")
+    }
+    fo := &FoulOdds{Reachable: make([][]int, 3)}
+    best := make([]*Hand, 3)
+    worst := make([]*Hand, 3)
+    for pos := Back; pos <= Front; pos++ {
+        var ranks map[int]int
+        ranks, best[pos], worst[pos] = reachable(pos, ch.Row(pos), live, ch.Low)
+        for rank := HighCard; rank <= FiveOfAKind; rank++ {
+            if ranks[rank] > 0 {
+                fo.Reachable[pos] = append(fo.Reachable[pos], rank)
+            }
+        }
+    }
+    fouls := 0.0
+    trials := 0.0
+    if choose(len(live), empty[Back]) * choose(len(live) - empty[Back], empty[Middle]) *
+        choose(len(live) - empty[Back] - empty[Middle], empty[Front]) <= foulExactLimit {
+        fo.Exact = true
+        combinations(live, empty[Back], func(b, rest []Card) {
+            back := withCards(ch.Back, b, ch.Low)
+            combinations(rest, empty[Middle], func(m, rest []Card) {
+                middle := withCards(ch.Middle, m, ch.Low)
+                if back.Compare(middle) < 0 {
+                    n := choose(len(rest), empty[Front])
+                    fouls += n
+                    trials += n
+                    return
+                }
+                combinations(rest, empty[Front], func(f, _ []Card) {
+                    if middle.Compare(withCards(ch.Front, f, ch.Low)) < 0 {
+                        fouls++
+                    }
+                    trials++
+                })
+            })
+        })
+    } else {
+        rnd, err := newRand(src)
+        if err != nil {
+            return nil, err
+        }
+        deck := append([]Card{}, live...)
+        for t := 0; t < samples; t++ {
+            for i := 0; i < total; i++ {
+                j := i + rnd.Intn(len(deck) - i)
+                deck[i], deck[j] = deck[j], deck[i]
+            }
+            done := &ChineseHand{
+                Back: withCards(ch.Back, deck[:empty[Back]], ch.Low),
+                Middle: withCards(ch.Middle, deck[empty[Back]:empty[Back]+empty[Middle]], ch.Low),
+                Front: withCards(ch.Front, deck[empty[Back]+empty[Middle]:total], ch.Low),
+            }
+            if done.Fault() {
+                fouls++
+            }
+            trials++
+        }
+    }
+    fo.Trials = int(trials)
+    if trials > 0 {
+        fo.Probability = fouls / trials
+    }
+    if fo.Exact {
+        fo.Fouled = fouls == trials
+    } else if fouls == trials {
+        fo.Fouled = ch.dead(live, empty[Middle], best, worst)
+    }
+    return fo, nil
+}
+
+// dead reports whether no completion can avoid a foul, treating the back and
+// front rows as free to take their best and worst hands respectively.  That
+// relaxation can only miss dead hands, never flag live ones.  When there are
+// too many ways to fill the middle only the row bounds are compared.
+func (ch *ChineseHand) dead(live []Card, empty int, best, worst []*Hand) bool {
+    if best[Back].Compare(worst[Middle]) < 0 || best[Middle].Compare(worst[Front]) < 0 {
+        return true
+    }
+    if choose(len(live), empty) > foulExactLimit {
+        return false
+    }
+    dead := true
+    combinations(live, empty, func(m, _ []Card) {
+        if dead {
+            middle := withCards(ch.Middle, m, ch.Low)
+            dead = best[Back].Compare(middle) < 0 || middle.Compare(worst[Front]) < 0
+        }
+    })
+    return dead
+}
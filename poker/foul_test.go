@@ -0,0 +1,102 @@
+package poker
+
+import (
+    "sync"
+    "testing"
+)
+
+func liveFor(ch *ChineseHand) []Card {
+    return remaining(StandardDeck.Ordered(), ch.Cards())
+}
+
+func TestOdds(t *testing.T) {
+    cases := []struct {
+        back, middle, front string
+        fouled bool
+        low, high float64  // bounds on the foul probability
+    }{
+        // A pair already in the middle beats the seven-high back.
+        {"2s3h4d5c7s", "KsKh9d8c", "2h3d4c", true, 1, 1},
+        // The back is made; the middle only fouls against the front if it
+        // stays below the front's eight high.
+        {"AsAhAdKcKs", "2s3h4d5c", "6s7h8d", false, 0.01, 0.99},
+        // Nothing can go wrong.
+        {"AsAhAdAcKs", "KhKd2s2h", "3s4h", false, 0, 0},
+    }
+    for _, c := range cases {
+        ch := chineseHand(t, c.back, c.middle, c.front)
+        fo, err := ch.Odds(liveFor(ch), foulSamples, SeededSource(1))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if !fo.Exact || fo.Fouled != c.fouled || fo.Probability < c.low || fo.Probability > c.high {
+            t.Errorf("%s/%s/%s: %+v", c.back, c.middle, c.front, fo)
+        }
+    }
+}
+
+func TestOddsReachable(t *testing.T) {
+    ch := chineseHand(t, "AsAhAdKcKs", "2s3s4s5s", "QhQd")
+    fo, err := ch.Odds(liveFor(ch), foulSamples, SeededSource(1))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !fo.CanMake(Middle, StraightFlush) || !fo.CanMake(Front, Trips) || fo.CanMake(Front, Quads) {
+        t.Errorf("reachable %v", fo.Reachable)
+    }
+}
+
+func TestOddsSampled(t *testing.T) {
+    ch := &ChineseHand{}
+    ch.Place(Back, Card(0))
+    first, err := ch.Odds(liveFor(ch), 500, SeededSource(7))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if first.Exact || first.Trials != 500 || first.Fouled {
+        t.Errorf("sampled odds %+v", first)
+    }
+    again, _ := ch.Odds(liveFor(ch), 500, SeededSource(7))
+    if again.Probability != first.Probability {
+        t.Errorf("same seed gave %v then %v", first.Probability, again.Probability)
+    }
+    if _, err := ch.Odds(liveFor(ch)[:5], 500, SeededSource(7)); err == nil {
+        t.Error("finished a hand from too few live cards")
+    }
+}
+
+// A sampled hand whose every completion fouls is only reported dead when no
+// way of filling the middle can save it.
+func TestDeadHand(t *testing.T) {
+    // The middle can make trip kings at best, which never beat the aces in
+    // front.
+    ch := chineseHand(t, "Kc", "KsKhQd2c", "AhAdAc")
+    fo, err := ch.Odds(liveFor(ch), foulSamples, SeededSource(1))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if fo.Exact || !fo.Fouled || fo.Probability != 1 {
+        t.Errorf("dead hand %+v", fo)
+    }
+    // Kings full in the middle under a club straight flush would hold up.
+    ch = chineseHand(t, "Kc", "KsKhQd", "AhAdAc")
+    if fo, _ = ch.Odds(liveFor(ch), foulSamples, SeededSource(1)); fo.Exact || fo.Fouled {
+        t.Errorf("live hand reported dead: %+v", fo)
+    }
+}
+
+// Odds is worked out for every player at once; run it under -race.
+func TestOddsConcurrent(t *testing.T) {
+    var wg sync.WaitGroup
+    for i := 0; i < 4; i++ {
+        wg.Add(1)
+        go func(seed int64) {
+            defer wg.Done()
+            ch := &ChineseHand{}
+            if _, err := ch.Odds(liveFor(ch), 200, SeededSource(seed)); err != nil {
+                t.Error(err)
+            }
+        }(int64(i))
+    }
+    wg.Wait()
+}
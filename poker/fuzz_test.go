@@ -0,0 +1,193 @@
+package poker
+
+import (
+    "fmt"
+    "testing"
+)
+
+// fuzzCards turns data into up to n distinct cards from a standard deck.
+func fuzzCards(data []byte, n int) []Card {
+    var seen CardSet
+    cards := make([]Card, 0, n)
+    for _, b := range data {
+        c := Card(int(b) % 52)
+        if len(cards) == n {
+            break
+        }
+        if !seen.Contains(c) {
+            seen = seen.Add(c)
+            cards = append(cards, c)
+        }
+    }
+    return cards
+}
+
+func distinct(cards []Card) bool {
+    return checkDistinct(cards) == nil
+}
+
+func FuzzNewHand(f *testing.F) {
+    f.Add([]byte{0, 1, 2, 3, 4}, []byte{13, 26, 39, 0, 12}, []byte{8, 9, 10, 11, 12})
+    f.Add([]byte{0, 13, 26}, []byte{1, 14, 27}, []byte{51, 50, 49})
+    f.Fuzz(func(t *testing.T, a, b, c []byte) {
+        hands := make([]*Hand, 0, 3)
+        for _, data := range [][]byte{a, b, c} {
+            cards := fuzzCards(data, 5)
+            h := NewHand(append([]Card{}, cards...))
+            if len(cards) == 0 {
+                if h != nil {
+                    t.Fatalf("no cards made %v", h)
+                }
+                continue
+            }
+            if h.Count() != len(cards) || !distinct(h.Cards()) {
+                t.Fatalf("%v made %v", cards, h.Cards())
+            }
+            hands = append(hands, h)
+        }
+        for _, x := range hands {
+            for _, y := range hands {
+                if sign(x.Compare(y)) != -sign(y.Compare(x)) {
+                    t.Fatalf("Compare is not antisymmetric on %v and %v", x.Cards(), y.Cards())
+                }
+                for _, z := range hands {
+                    if x.Compare(y) >= 0 && y.Compare(z) >= 0 && x.Compare(z) < 0 {
+                        t.Fatalf("Compare is not transitive on %v, %v and %v", x.Cards(), y.Cards(), z.Cards())
+                    }
+                }
+            }
+        }
+    })
+}
+
+func FuzzHandAdd(f *testing.F) {
+    f.Add([]byte{0, 1, 2}, byte(1))
+    f.Add([]byte{5, 18, 31, 44}, byte(7))
+    f.Fuzz(func(t *testing.T, data []byte, extra byte) {
+        cards := fuzzCards(data, 5)
+        var h *Hand
+        for _, card := range cards {
+            h = h.Add(card)
+        }
+        if h.Count() != len(cards) || !distinct(h.Cards()) {
+            t.Fatalf("adding %v made %v", cards, h.Cards())
+        }
+        added := h.Add(Card(int(extra) % 52))
+        if added != nil && (added.Count() > 5 || !distinct(added.Cards())) {
+            t.Fatalf("adding %v to %v made %v", Card(int(extra) % 52), h.Cards(), added.Cards())
+        }
+    })
+}
+
+func FuzzHandFix(f *testing.F) {
+    f.Add([]byte{0, 13, 26, 1, 14})
+    f.Fuzz(func(t *testing.T, data []byte) {
+        cards := fuzzCards(data, 5)
+        h := NewHand(cards)
+        fixed := h.Fix()
+        if h.Compare(fixed) != 0 || fixed.Count() != h.Count() {
+            t.Fatalf("Fix changed %v to %v", h, fixed)
+        }
+        set, _ := NewCardSet(h.Cards())
+        fixedSet, _ := NewCardSet(fixed.Cards())
+        if set != fixedSet {
+            t.Fatalf("Fix changed the cards of %v to %v", h.Cards(), fixed.Cards())
+        }
+    })
+}
+
+func FuzzRoyalties(f *testing.F) {
+    f.Add([]byte{12, 25, 38, 51, 11, 24, 37, 50, 10, 23, 36, 9, 22}, []byte{0, 0, 0, 0, 0, 1, 1, 1, 1, 1, 2, 2, 2})
+    f.Fuzz(func(t *testing.T, data, positions []byte) {
+        cards := fuzzCards(data, 13)
+        ch := &ChineseHand{}
+        for i, card := range cards {
+            pos := 0
+            if i < len(positions) {
+                pos = int(positions[i]) % 3
+            }
+            // A full row takes the card in the next row with room.
+            for tries := 0; tries < 3 && ch.Place(pos, card) != nil; tries++ {
+                pos = (pos + 1) % 3
+            }
+        }
+        for pos := Back; pos <= Front; pos++ {
+            if ch.Row(pos).Count() > RowSize(pos) {
+                t.Fatalf("row %d holds %v", pos, ch.Row(pos).Cards())
+            }
+        }
+        if len(ch.Cards()) != len(cards) || !distinct(ch.Cards()) {
+            t.Fatalf("placed %v but hand holds %v", cards, ch.Cards())
+        }
+        r := ch.Royalties()
+        if r < 0 {
+            t.Fatalf("negative royalties %d", r)
+        }
+        if len(cards) == 13 && ch.Fault() && r != 0 {
+            t.Fatalf("fouled hand scored %d", r)
+        }
+    })
+}
+
+func FuzzGame(f *testing.F) {
+    f.Add(byte(2), int64(1), []byte{0, 1, 2, 3, 4, 5, 6, 7})
+    f.Add(byte(4), int64(7), []byte{9, 200, 31, 77})
+    f.Fuzz(func(t *testing.T, players byte, seed int64, moves []byte) {
+        gs, err := NewGame(0, StandardDeck, SeededSource(seed))
+        if err != nil {
+            t.Fatal(err)
+        }
+        n := 1 + int(players) % gs.MaxPlayers()
+        for i := 0; i < n; i++ {
+            if err := gs.Sit(fmt.Sprint("p", i), "", fmt.Sprint(seed, i), ""); err != nil {
+                t.Fatal(err)
+            }
+        }
+        if err := gs.Start(); err != nil {
+            t.Fatal(err)
+        }
+        // Play the moves given, then finish the game with any legal ones.
+        for step := 0; !gs.Finished(); step++ {
+            if step > 13 * n * 3 {
+                t.Fatalf("game did not finish")
+            }
+            b := 0
+            if step < len(moves) {
+                b = int(moves[step])
+            }
+            idx, pos := b % len(gs.Showing), b / 8 % 3
+            for tries := 0; tries < 3; tries++ {
+                if err = gs.Play(gs.Players[gs.Turn], idx, (pos + tries) % 3); err == nil {
+                    break
+                }
+            }
+            if err != nil {
+                t.Fatalf("no legal play: %v", err)
+            }
+        }
+        for i, hand := range gs.Hands {
+            if len(hand.Cards()) != 13 {
+                t.Fatalf("hand %d finished with %v", i, hand.Cards())
+            }
+            for pos := Back; pos <= Front; pos++ {
+                if hand.Row(pos).Count() != RowSize(pos) {
+                    t.Fatalf("hand %d row %d holds %v", i, pos, hand.Row(pos).Cards())
+                }
+            }
+        }
+        if !distinct(gs.Deck[:gs.DeckPos]) {
+            t.Fatalf("dealt a card twice: %v", gs.Deck[:gs.DeckPos])
+        }
+        placed := make([]Card, 0)
+        for _, hand := range gs.Hands {
+            placed = append(placed, hand.Cards()...)
+        }
+        if !distinct(placed) || len(placed) != gs.DeckPos {
+            t.Fatalf("placed %v from %v", placed, gs.Deck[:gs.DeckPos])
+        }
+        if err := (&Fairness{Spec: gs.Spec, Commitment: Commitment(gs.ServerSeed), ClientSeeds: gs.ClientSeeds,
+            ServerSeed: gs.ServerSeed, Dealt: gs.Deck[:gs.DeckPos]}).Verify(); err != nil {
+            t.Fatal(err)
+        }
+    })
+}
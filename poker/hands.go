@@ -8,6 +8,9 @@ import (
 type Hand struct {
     Royalty Royalty
     Kickers []Card
+    Low Rank  // lowest rank in the deck the hand was dealt from
+    Wild []Card  // jokers in the hand
+    Subs []Card  // the cards the jokers stand for, among Royalty and Kickers
 }
 
 func (h *Hand) Count() int {
@@ -17,6 +20,24 @@ func (h *Hand) Count() int {
     return len(h.Kickers) + len(h.Royalty.Cards)
 }
 
+func (h *Hand) Cards() []Card {
+    if (h == nil) {
+        return nil
+    }
+    cards := make([]Card, 0, h.Count())
+    cards = append(cards, h.Royalty.Cards...)
+    cards = append(cards, h.Kickers...)
+    for _, sub := range h.Subs {
+        for i, card := range cards {
+            if card == sub {
+                cards = append(cards[:i], cards[i+1:]...)
+                break
+            }
+        }
+    }
+    return append(cards, h.Wild...)
+}
+
 func (h *Hand) String() string {
     return fmt.Sprintf("%s Kickers: %v", h.Royalty, h.Kickers)
 }
@@ -25,34 +46,18 @@ func (h *Hand) Fix() *Hand {
     if (h == nil) {
         return nil
     }
-    var nh *Hand;
-    for _, card := range h.Royalty.Cards {
-        nh = nh.Add(card)
-    }
-    for _, card := range h.Kickers {
-        nh = nh.Add(card)
-    }
-    return nh
+    return newHand(h.Cards(), h.Low)
 }
 
 func (h *Hand) Add(c Card) *Hand {
     if (h == nil) {
         return NewHand([]Card{c})
     }
-    cards := h.Royalty.Cards
-    for _, card := range h.Kickers {
-        cards = append(cards, card)
-    }
-    found := false
-    for _, other := range cards {
-        if other == c {
-            found = true
-        }
-    }
-    if !found {
+    cards := h.Cards()
+    if !holds(cards, c) {
         cards = append(cards, c)
     }
-    return NewHand(cards)
+    return newHand(cards, h.Low)
 }
 
 type CardList []Card
@@ -84,26 +89,26 @@ func flushHand(cards []Card) *Hand {
     return &Hand{Royalty: Royalty{Flush, cards}, Kickers: nil}
 }
 
-func aceLowStraightHand(cards []Card) *Hand {
+func aceLowStraightHand(cards []Card, low Rank) *Hand {
     if len(cards) == 5 &&
-        cards[0].Rank() == 0 &&
-        cards[1].Rank() == 1 &&
-        cards[2].Rank() == 2 &&
-        cards[3].Rank() == 3 &&
+        cards[0].Rank() == low &&
+        cards[1].Rank() == low + 1 &&
+        cards[2].Rank() == low + 2 &&
+        cards[3].Rank() == low + 3 &&
         cards[4].Rank() == 12 {
         return &Hand{Royalty: Royalty{AceLowStraight, cards}, Kickers: nil}
     }
     return nil
 }
 
-func straightHand(cards []Card) *Hand {
+func straightHand(cards []Card, low Rank) *Hand {
     if len(cards) <= 4 {
         return nil
     }
     r := cards[0].Rank() - 1
     for _, card := range cards {
         if card.Rank() != r + 1 {
-            return aceLowStraightHand(cards)
+            return aceLowStraightHand(cards, low)
         }
         r++
     }
@@ -128,6 +133,9 @@ func quadsHand(cards []Card) *Hand {
     if len(cards) <= 3 {
         return nil
     }
+    if q := filter(cards, cards[0].Rank(), false); len(q) == 5 {
+        return &Hand{Royalty: Royalty{FiveOfAKind, q}, Kickers: nil}
+    }
     if q := filter(cards, cards[0].Rank(), false); len(q) == 4 {
         return &Hand{
             Royalty: Royalty{Quads, q},
@@ -144,8 +152,10 @@ func quadsHand(cards []Card) *Hand {
 func maybeFullHouse(cards []Card, tripsRank Rank) *Hand {
     kickers := filter(cards, tripsRank, true)
     if h := pairsHand(kickers); h != nil {
+        // The pair goes first so that Compare, working from the end, weighs
+        // the trips before the pair.
         return &Hand{
-            Royalty: Royalty{FullHouse, cards},
+            Royalty: Royalty{FullHouse, append(h.Royalty.Cards, filter(cards, tripsRank, false)...)},
             Kickers: h.Kickers}
     }
     return &Hand{
@@ -183,8 +193,11 @@ func pairsHand(cards []Card) *Hand {
             cards := []Card{cards[i], cards[i + 1]}
             r := Pair
             if tp := pairsHand(kickers); tp != nil {
-                for _, card := range tp.Royalty.Cards {
-                    cards = append(cards, card)
+                // Keep the higher pair last, whichever was found first.
+                if tp.Royalty.Cards[0].Rank() > cards[0].Rank() {
+                    cards = append(cards, tp.Royalty.Cards[:2]...)
+                } else {
+                    cards = append(append([]Card{}, tp.Royalty.Cards[:2]...), cards...)
                 }
                 kickers = tp.Kickers
                 r = TwoPair
@@ -197,43 +210,230 @@ func pairsHand(cards []Card) *Hand {
     return nil
 }
 
-func straightOrFlushHand(cards []Card) *Hand {
+func straightOrFlushHand(cards []Card, low Rank) *Hand {
     hand := flushHand(cards)
     if hand != nil {
-        if straight := straightHand(hand.Royalty.Cards); straight != nil {
+        if straight := straightHand(hand.Royalty.Cards, low); straight != nil {
             if straight.Royalty.Rank == AceLowStraight {
                 return &Hand{Royalty: Royalty{AceLowStraightFlush, hand.Royalty.Cards}, Kickers: hand.Kickers}
             }
-            if cards[0] == 8 {  // ace high
+            if cards[0].Rank() == 8 {  // ten to ace
                 return &Hand{Royalty: Royalty{Royal, hand.Royalty.Cards}, Kickers: hand.Kickers}
             }
             return &Hand{Royalty: Royalty{StraightFlush, hand.Royalty.Cards}, Kickers: hand.Kickers}
         }
         return hand
     }
-    return straightHand(cards)
+    return straightHand(cards, low)
 }
 
+// NewHand ranks up to five cards dealt from a standard deck.
 func NewHand(cards []Card) *Hand {
+    return newHand(cards, 0)
+}
+
+// newHand ranks cards dealt from a deck whose lowest rank is low.  In a short
+// deck (low above zero) the wheel runs from the ace to low + 3, and a flush
+// beats a full house.  Jokers stand for whichever cards make the best hand.
+func newHand(cards []Card, low Rank) *Hand {
     if len(cards) > 5 || len(cards) < 1 {
         return nil
     }
     sort.Sort(CardList(cards))
-    if hand := straightOrFlushHand(cards); hand != nil {
-        return hand
+    if cards[0].IsJoker() {
+        return wildHand(cards, low)
     }
-    if hand := quadsHand(cards); hand != nil {
-        return hand
+    return rankHand(cards, low)
+}
+
+// holds reports whether c is among cards.
+func holds(cards []Card, c Card) bool {
+    if set, ok := NewCardSet(cards); ok {
+        return set.Contains(c)
     }
-    if hand := tripsHand(cards); hand != nil {
-        return hand
+    return contains(cards, c)
+}
+
+// rankHand ranks sorted cards that include no jokers.  Distinct cards from
+// a single deck are ranked from the set's rank and suit masks; copies, from
+// several decks or stood for by jokers, need the slower list-based path.
+func rankHand(cards []Card, low Rank) *Hand {
+    if set, ok := NewCardSet(cards); ok && set.Count() == len(cards) {
+        return rankSet(cards, set, low)
+    }
+    return rankCards(cards, low)
+}
+
+// lowestRank is the lowest rank among the bits of ranks.
+func lowestRank(ranks int) Rank {
+    r := Rank(0)
+    for ranks & 1 == 0 {
+        ranks >>= 1
+        r++
     }
-    if hand := pairsHand(cards); hand != nil {
-        return hand
+    return r
+}
+
+// rankSet ranks sorted cards from a single deck, all of them in set.  The
+// cards are laid out as rankCards would lay them out, so the two agree.
+func rankSet(cards []Card, set CardSet, low Rank) *Hand {
+    s0, s1, s2, s3 := set.Suit(0), set.Suit(1), set.Suit(2), set.Suit(3)
+    // The ranks held at least twice, three and four times.
+    twice := s0 & (s1 | s2 | s3) | s1 & (s2 | s3) | s2 & s3
+    thrice := s0 & s1 & (s2 | s3) | (s0 | s1) & s2 & s3
+    quads := s0 & s1 & s2 & s3
+    pairs := twice &^ thrice
+    var hand *Hand
+    switch {
+        case quads != 0:
+            r := lowestRank(quads)
+            hand = &Hand{Royalty: Royalty{Quads, filter(cards, r, false)}, Kickers: filter(cards, r, true)}
+        case thrice != 0 && pairs != 0:
+            t, p := lowestRank(thrice), lowestRank(pairs)
+            hand = &Hand{
+                Royalty: Royalty{FullHouse, append(filter(cards, p, false), filter(cards, t, false)...)},
+                Kickers: []Card{}}
+        case thrice != 0:
+            r := lowestRank(thrice)
+            hand = &Hand{Royalty: Royalty{Trips, filter(cards, r, false)}, Kickers: filter(cards, r, true)}
+        case pairs != 0:
+            r := lowestRank(pairs)
+            made := filter(cards, r, false)
+            kickers := filter(cards, r, true)
+            rank := Pair
+            if rest := pairs &^ (1 << uint(r)); rest != 0 {
+                high := lowestRank(rest)
+                made = append(made, filter(kickers, high, false)...)
+                kickers = filter(kickers, high, true)
+                rank = TwoPair
+            }
+            hand = &Hand{Royalty: Royalty{rank, made}, Kickers: kickers}
+        case len(cards) == 5:
+            hand = straightOrFlushSet(cards, set, low)
     }
-    return &Hand{
-        Royalty: Royalty{HighCard, []Card{cards[len(cards)-1]}},
-        Kickers: cards[0:len(cards)-1]}
+    if hand == nil {
+        hand = &Hand{
+            Royalty: Royalty{HighCard, []Card{cards[len(cards)-1]}},
+            Kickers: cards[0:len(cards)-1]}
+    }
+    hand.Low = low
+    return hand
+}
+
+// straightOrFlushSet ranks five sorted cards of different ranks, or returns
+// nil if they are neither a straight nor a flush.
+func straightOrFlushSet(cards []Card, set CardSet, low Rank) *Hand {
+    ranks := set.Ranks()
+    flush := set.Suit(cards[0].Suit()) == ranks
+    wheel := ranks == 1 << 12 | 0xf << uint(low)
+    straight := wheel || ranks == 0x1f << uint(cards[0].Rank())
+    rank := HighCard
+    switch {
+        case flush && wheel:
+            rank = AceLowStraightFlush
+        case flush && straight && cards[0].Rank() == 8:  // ten to ace
+            rank = Royal
+        case flush && straight:
+            rank = StraightFlush
+        case flush:
+            rank = Flush
+        case wheel:
+            rank = AceLowStraight
+        case straight:
+            rank = Straight
+        default:
+            return nil
+    }
+    return &Hand{Royalty: Royalty{rank, cards}, Kickers: nil}
+}
+
+// rankCards ranks sorted cards that include no jokers, by working through
+// the possible hands from the top.  It copes with several decks, where a
+// flush can also hold a pair or better.
+func rankCards(cards []Card, low Rank) *Hand {
+    hand := straightOrFlushHand(cards, low)
+    if hand == nil || hand.Royalty.Rank == Flush {
+        other := quadsHand(cards)
+        if other == nil {
+            other = tripsHand(cards)
+        }
+        if other == nil {
+            other = pairsHand(cards)
+        }
+        if other != nil {
+            other.Low = low
+            if hand == nil || other.Compare(&Hand{Royalty: hand.Royalty, Low: low}) > 0 {
+                hand = other
+            }
+        }
+    }
+    if hand == nil {
+        hand = &Hand{
+            Royalty: Royalty{HighCard, []Card{cards[len(cards)-1]}},
+            Kickers: cards[0:len(cards)-1]}
+    }
+    hand.Low = low
+    return hand
+}
+
+// wildHand tries every set of ranks the jokers (sorted first) could take,
+// once with them all in the suit of the other cards, so as to make any
+// flush, and once spread over other suits.  Suits matter for nothing else.
+func wildHand(cards []Card, low Rank) *Hand {
+    n := 0
+    for n < len(cards) && cards[n].IsJoker() {
+        n++
+    }
+    wild, natural := cards[:n], cards[n:]
+    flushSuit := Suit(0)
+    if len(natural) > 0 {
+        flushSuit = natural[0].Suit()
+    }
+    var best *Hand
+    var bestSubs []Card
+    subs := make([]Card, n)
+    var rec func(i int, from Rank, flush bool)
+    rec = func(i int, from Rank, flush bool) {
+        if i == n {
+            hand := rankHand(sortedCards(append(append([]Card{}, natural...), subs...)), low)
+            if best == nil || hand.Compare(best) > 0 {
+                best = hand
+                bestSubs = append([]Card{}, subs...)
+            }
+            return
+        }
+        for r := from; r <= 12; r++ {
+            s := flushSuit
+            if !flush {
+                s = (flushSuit + 1 + Suit(i % 2)) % 4
+            }
+            subs[i] = NewCard(r, s)
+            rec(i + 1, r, flush)
+        }
+    }
+    rec(0, low, true)
+    rec(0, low, false)
+    best.Wild = append([]Card{}, wild...)
+    best.Subs = bestSubs
+    return best
+}
+
+func sortedCards(cards []Card) []Card {
+    sort.Sort(CardList(cards))
+    return cards
+}
+
+// category orders the hand's rank for comparison.
+func (h *Hand) category() int {
+    if h.Low > 0 {
+        switch (h.Royalty.Rank) {
+            case Flush:
+                return FullHouse
+            case FullHouse:
+                return Flush
+        }
+    }
+    return h.Royalty.Rank
 }
 
 func min(x, y int) int {
@@ -269,7 +469,7 @@ func (h *Hand) Compare(other *Hand) int {
     if other == nil {
         return 1
     }
-    d := h.Royalty.Rank - other.Royalty.Rank
+    d := h.category() - other.category()
     if d != 0 {
         return d
     }
@@ -0,0 +1,207 @@
+package poker
+
+import (
+    "sort"
+    "testing"
+)
+
+// referenceScore ranks sorted cards independently of NewHand: the category,
+// then the ranks ordered by how often they appear and then from the top down.
+// Straights are scored by their top card, so the wheel comes lowest.  Hands
+// with equal scores are equal.
+func referenceScore(cards []Card, low Rank) []int {
+    counts := make([]int, 13)
+    suits := make(map[Suit]bool)
+    for _, card := range cards {
+        counts[card.Rank()]++
+        suits[card.Suit()] = true
+    }
+    ranks := make([]int, 0, len(cards))
+    for n := 4; n >= 1; n-- {
+        for r := 12; r >= 0; r-- {
+            if counts[r] == n {
+                ranks = append(ranks, r)
+            }
+        }
+    }
+    flush := len(cards) == 5 && len(suits) == 1
+    straight := false
+    if len(cards) == 5 && len(ranks) == 5 {
+        if ranks[0] - ranks[4] == 4 {
+            straight = true
+        } else if ranks[0] == 12 && ranks[1] == int(low) + 3 && ranks[4] == int(low) {
+            // The wheel: the ace plays low.
+            straight = true
+            ranks = append(ranks[1:], -1)
+        }
+    }
+    // Categories from worst to best.
+    const (
+        high = iota
+        pair
+        twoPair
+        trips
+        straightCat
+        fullHouseCat
+        flushCat
+        quads
+        straightFlush
+    )
+    category := high
+    switch {
+        case straight && flush:
+            category = straightFlush
+        case counts[ranks[0]] == 4:
+            category = quads
+        case len(ranks) > 1 && counts[ranks[0]] == 3 && counts[ranks[1]] == 2:
+            category = fullHouseCat
+        case flush:
+            category = flushCat
+        case straight:
+            category = straightCat
+        case counts[ranks[0]] == 3:
+            category = trips
+        case len(ranks) > 1 && counts[ranks[0]] == 2 && counts[ranks[1]] == 2:
+            category = twoPair
+        case counts[ranks[0]] == 2:
+            category = pair
+    }
+    // Outside a short deck a full house beats a flush.
+    if low == 0 && category == flushCat {
+        category = fullHouseCat
+    } else if low == 0 && category == fullHouseCat {
+        category = flushCat
+    }
+    return append([]int{category}, ranks...)
+}
+
+func compareScores(a, b []int) int {
+    for i := 0; i < len(a) && i < len(b); i++ {
+        if a[i] != b[i] {
+            return a[i] - b[i]
+        }
+    }
+    return len(a) - len(b)
+}
+
+func sign(x int) int {
+    switch {
+        case x < 0:
+            return -1
+        case x > 0:
+            return 1
+    }
+    return 0
+}
+
+type scoredHand struct {
+    hand *Hand
+    score []int
+}
+
+// checkAgainstReference ranks every k card hand from spec with both NewHand
+// and the reference, groups them into classes of equal reference score, and
+// checks that Compare agrees on every pair of classes and within each class.
+func checkAgainstReference(t *testing.T, spec DeckSpec, k int) {
+    hands := make([]scoredHand, 0)
+    combinations(spec.Ordered(), k, func(chosen, _ []Card) {
+        cards := append([]Card{}, chosen...)
+        h := spec.NewHand(cards)
+        hands = append(hands, scoredHand{h, referenceScore(h.Cards(), spec.Low)})
+    })
+    sort.Slice(hands, func(i, j int) bool {
+        return compareScores(hands[i].score, hands[j].score) < 0
+    })
+    classes := make([]scoredHand, 0)
+    for _, sh := range hands {
+        if len(classes) > 0 && compareScores(classes[len(classes)-1].score, sh.score) == 0 {
+            rep := classes[len(classes)-1]
+            if d := sh.hand.Compare(rep.hand); d != 0 {
+                t.Fatalf("%v and %v should tie but Compare gives %d", sh.hand.Cards(), rep.hand.Cards(), d)
+            }
+            continue
+        }
+        classes = append(classes, sh)
+    }
+    for i, a := range classes {
+        for j, b := range classes {
+            if testing.Short() && j != i + 1 {
+                continue
+            }
+            if got, want := sign(a.hand.Compare(b.hand)), sign(i - j); got != want {
+                t.Fatalf("Compare(%v, %v) = %d, want %d", a.hand.Cards(), b.hand.Cards(), got, want)
+            }
+        }
+    }
+}
+
+func TestCompareFiveCards(t *testing.T) {
+    checkAgainstReference(t, StandardDeck, 5)
+}
+
+func TestCompareThreeCards(t *testing.T) {
+    checkAgainstReference(t, StandardDeck, 3)
+}
+
+func TestCompareShortDeck(t *testing.T) {
+    checkAgainstReference(t, ShortDeck, 5)
+    checkAgainstReference(t, ShortDeck, 3)
+}
+
+func TestCategories(t *testing.T) {
+    tests := []struct {
+        cards string
+        rank int
+    }{
+        {"AsKsQsJsTs", Royal},
+        {"AhKhQhJhTh", Royal},
+        {"KsQsJsTs9s", StraightFlush},
+        {"As2s3s4s5s", AceLowStraightFlush},
+        {"AsAhAdAc2s", Quads},
+        {"KsKhKd2s2h", FullHouse},
+        {"As9s7s5s3s", Flush},
+        {"Ts9h8d7c6s", Straight},
+        {"As2h3d4c5s", AceLowStraight},
+        {"7s7h7d2c3s", Trips},
+        {"7s7h2d2c3s", TwoPair},
+        {"7s7h2d4c3s", Pair},
+        {"As9h7d5c3s", HighCard},
+        {"AsAhAd", Trips},
+        {"AsAh2d", Pair},
+    }
+    for _, test := range tests {
+        cards, err := ParseCards(test.cards)
+        if err != nil {
+            t.Fatal(err)
+        }
+        if h := NewHand(cards); h.Royalty.Rank != test.rank {
+            t.Errorf("%s ranked %v", test.cards, h.Royalty)
+        }
+    }
+}
+
+func TestCompareCases(t *testing.T) {
+    tests := []struct {
+        a, b string
+        want int
+    }{
+        // Full houses go by the trips, then the pair.
+        {"QsQhQdAsAh", "KsKhKd2s2h", -1},
+        {"KsKhKd3s3h", "KcKhKd2s2h", 1},
+        // Two pair goes by the top pair, then the bottom pair, then the kicker.
+        {"AsAh2d2c3s", "KsKhQdQcJs", 1},
+        {"AsAh3d3c2s", "AdAc2h2s4s", 1},
+        {"AsAh3d3c5s", "AdAc3h3s4s", 1},
+        // The wheel is the lowest straight.
+        {"As2h3d4c5s", "2s3h4d5c6s", -1},
+        {"AsKsQsJsTs", "KhQhJhTh9h", 1},
+        {"AsKsQsJsTs", "AhKhQhJhTh", 0},
+    }
+    for _, test := range tests {
+        a, _ := ParseCards(test.a)
+        b, _ := ParseCards(test.b)
+        if got := sign(NewHand(a).Compare(NewHand(b))); got != test.want {
+            t.Errorf("Compare(%s, %s) = %d, want %d", test.a, test.b, got, test.want)
+        }
+    }
+}
@@ -0,0 +1,127 @@
+package poker
+
+import (
+    "appengine"
+    "appengine/datastore"
+    "fmt"
+    "net/http"
+    "time"
+)
+
+// Balance is a player's running total over every table they have played at
+// for stakes.
+type Balance struct {
+    Player string
+    Points int
+    Updated time.Time
+}
+
+// RecordHand writes the finished hand's transfers to the ledger, under the
+// game.  Call it in the transaction that saves the game: the entries are
+// keyed by the hand, so a retried transaction rewrites them rather than
+// adding them twice.  UpdateBalances then carries them to the players.
+func (gs *GameState) RecordHand(c appengine.Context) error {
+    if gs.Stakes == nil || !gs.Finished() || gs.key == nil {
+        return nil
+    }
+    now := gs.now()
+    keys := make([]*datastore.Key, 0)
+    entries := make([]*LedgerEntry, 0)
+    for _, t := range NewShowdown(gs.Hands).Transfers() {
+        name := fmt.Sprintf("%d/%d/%d", gs.HandsPlayed, t.From, t.To)
+        keys = append(keys, datastore.NewKey(c, "LedgerEntry", name, 0, gs.key))
+        entries = append(entries, &LedgerEntry{
+            Game: gs.Id(),
+            Hand: gs.HandsPlayed,
+            From: gs.Players[t.From],
+            To: gs.Players[t.To],
+            FromName: gs.PlayerNames[t.From],
+            ToName: gs.PlayerNames[t.To],
+            Points: t.Points,
+            PointValue: gs.Stakes.PointValue,
+            Time: now,
+        })
+    }
+    if len(entries) == 0 {
+        return nil
+    }
+    _, err := datastore.PutMulti(c, keys, entries)
+    return err
+}
+
+// balancedHand marks a hand as counted in a player's balance.  It is stored
+// under the balance, so that the two are written together.
+type balancedHand struct {
+    Time time.Time
+}
+
+// UpdateBalances adds the transfers recorded for a hand of the game id to
+// the players' balances.  Each balance counts a hand once, however often
+// this runs.
+func UpdateBalances(id string, hand int, r *http.Request) error {
+    key, err := datastore.DecodeKey(id)
+    if err != nil {
+        return err
+    }
+    c := appengine.NewContext(r)
+    entries := make([]*LedgerEntry, 0)
+    if _, err := datastore.NewQuery("LedgerEntry").Ancestor(key).Filter("Hand =", hand).GetAll(c, &entries); err != nil {
+        return err
+    }
+    change := make(map[string]int)
+    for _, e := range entries {
+        change[e.From] -= e.Points
+        change[e.To] += e.Points
+    }
+    now := time.Now()
+    for player, points := range change {
+        key := datastore.NewKey(c, "Balance", player, 0, nil)
+        done := datastore.NewKey(c, "BalancedHand", fmt.Sprintf("%s/%d", id, hand), 0, key)
+        err := datastore.RunInTransaction(c, func(c appengine.Context) error {
+            if err := datastore.Get(c, done, &balancedHand{}); err == nil {
+                return nil
+            } else if err != datastore.ErrNoSuchEntity {
+                return err
+            }
+            b := &Balance{Player: player}
+            if err := datastore.Get(c, key, b); err != nil && err != datastore.ErrNoSuchEntity {
+                return err
+            }
+            b.Points += points
+            b.Updated = now
+            if _, err := datastore.Put(c, key, b); err != nil {
+                return err
+            }
+            _, err := datastore.Put(c, done, &balancedHand{now})
+            return err
+        }, nil)
+        if err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// LoadLedger returns every transfer recorded at the game, oldest first.
+func LoadLedger(id string, r *http.Request) ([]*LedgerEntry, error) {
+    key, err := datastore.DecodeKey(id)
+    if err != nil {
+        return nil, err
+    }
+    c := appengine.NewContext(r)
+    entries := make([]*LedgerEntry, 0)
+    if _, err := datastore.NewQuery("LedgerEntry").Ancestor(key).Order("Time").GetAll(c, &entries); err != nil {
+        return nil, err
+    }
+    return entries, nil
+}
+
+func LoadBalance(player string, r *http.Request) (*Balance, error) {
+    c := appengine.NewContext(r)
+    b := &Balance{Player: player}
+    err := datastore.Get(c, datastore.NewKey(c, "Balance", player, 0, nil), b)
+    if err != nil && err != datastore.ErrNoSuchEntity {
+        return nil, err
+    }
+    return b, nil
+}
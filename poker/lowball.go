@@ -0,0 +1,159 @@
+package poker
+
+import (
+    "fmt"
+    "sort"
+)
+
+// LowHand is a five card hand ranked for lowball.  A smaller Value is a
+// better low; use Compare rather than reading Value directly.
+type LowHand struct {
+    Cards []Card
+    Value int
+}
+
+func (h *LowHand) String() string {
+    if h == nil {
+        return "No low"
+    }
+    return fmt.Sprintf("Low %v", h.Cards)
+}
+
+// Compare returns a positive number if h is the better low, negative if
+// other is, and zero for a tie.  A nil hand (no qualifying low) loses to any
+// other hand.
+func (h *LowHand) Compare(other *LowHand) int {
+    if h == nil {
+        if other == nil {
+            return 0
+        }
+        return -1
+    }
+    if other == nil {
+        return 1
+    }
+    return other.Value - h.Value
+}
+
+// lowRank places the ace below the deuce.
+func lowRank(c Card) int {
+    return (int(c.Rank()) + 1) % 13
+}
+
+// lowValue scores exactly five cards; smaller is better.  With aceLow the
+// ace counts below the deuce and straights and flushes are ignored, as in
+// ace-to-five.  Otherwise the ace is always high and straights and flushes
+// count against the hand, as in deuce-to-seven.
+func lowValue(cards []Card, aceLow bool) int {
+    counts := make([]int, 13)
+    for _, card := range cards {
+        r := int(card.Rank())
+        if aceLow {
+            r = lowRank(card)
+        }
+        counts[r]++
+    }
+    // Order the ranks by how often they appear, then from the top down.
+    ranks := make([]int, 0, 5)
+    for n := 4; n >= 1; n-- {
+        for r := 12; r >= 0; r-- {
+            if counts[r] == n {
+                ranks = append(ranks, r)
+            }
+        }
+    }
+    category := HighCard
+    switch {
+        case counts[ranks[0]] == 4:
+            category = Quads
+        case counts[ranks[0]] == 3 && counts[ranks[1]] == 2:
+            category = FullHouse
+        case counts[ranks[0]] == 3:
+            category = Trips
+        case counts[ranks[0]] == 2 && counts[ranks[1]] == 2:
+            category = TwoPair
+        case counts[ranks[0]] == 2:
+            category = Pair
+        case !aceLow:
+            straight := ranks[0] - ranks[4] == 4
+            flush := true
+            for _, card := range cards {
+                if card.Suit() != cards[0].Suit() {
+                    flush = false
+                }
+            }
+            if straight && flush {
+                category = StraightFlush
+            } else if flush {
+                category = Flush
+            } else if straight {
+                category = Straight
+            }
+    }
+    v := category
+    for i := 0; i < 5; i++ {
+        v *= 13
+        if i < len(ranks) {
+            v += ranks[i]
+        }
+    }
+    return v
+}
+
+func bestLow(cards []Card, aceLow bool) *LowHand {
+    if len(cards) < 5 {
+        return nil
+    }
+    var best *LowHand
+    combinations(cards, 5, func(chosen, _ []Card) {
+        h := &LowHand{append([]Card{}, chosen...), lowValue(chosen, aceLow)}
+        if h.Compare(best) > 0 {
+            best = h
+        }
+    })
+    sort.Sort(CardList(best.Cards))
+    return best
+}
+
+// AceToFive returns the best ace-to-five low from five or more cards: aces
+// are low, and straights and flushes do not count.
+func AceToFive(cards []Card) *LowHand {
+    return bestLow(cards, true)
+}
+
+// DeuceToSeven returns the best deuce-to-seven low from five or more cards:
+// aces are high, and straights and flushes count against the hand.
+func DeuceToSeven(cards []Card) *LowHand {
+    return bestLow(cards, false)
+}
+
+// Qualifies reports whether an ace-to-five low has five different ranks, all
+// no higher than high (e.g. 6 for eight-or-better).
+func (h *LowHand) Qualifies(high Rank) bool {
+    if h == nil {
+        return false
+    }
+    seen := make(map[int]bool)
+    for _, card := range h.Cards {
+        if seen[lowRank(card)] || lowRank(card) > (int(high) + 1) % 13 {
+            return false
+        }
+        seen[lowRank(card)] = true
+    }
+    return true
+}
+
+// OmahaLow returns the best eight-or-better low using exactly two hole cards
+// and three board cards, or nil if there is none.
+func OmahaLow(hole, board []Card) *LowHand {
+    var best *LowHand
+    combinations(hole, 2, func(h, _ []Card) {
+        combinations(board, 3, func(b, _ []Card) {
+            low := AceToFive(append(append([]Card{}, h...), b...))
+            if low.Qualifies(6) && low.Compare(best) > 0 {
+                best = low
+            }
+        })
+    })
+    return best
+}
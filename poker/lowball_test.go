@@ -0,0 +1,81 @@
+package poker
+
+import (
+    "testing"
+)
+
+func TestLowOrdering(t *testing.T) {
+    tests := []struct {
+        low func([]Card) *LowHand
+        a, b string
+        want int
+    }{
+        // Ace-to-five: the wheel is best, suits and straights don't matter.
+        {AceToFive, "As2h3d4c5s", "As2h3d4c6s", 1},
+        {AceToFive, "As2s3s4s5s", "Ah2d3c4s5h", 0},
+        {AceToFive, "6s5h4d3c2s", "7s4h3d2cAs", 1},
+        // Any five different ranks beat a pair.
+        {AceToFive, "KsQhJdTc9s", "AsAh2d3c4s", 1},
+        {AceToFive, "AsAh2d3c4s", "AsAh2d3c5s", 1},
+        // Deuce-to-seven: the ace is high and straights and flushes count.
+        {DeuceToSeven, "2s3h4d5c7s", "2s3h4d5c8s", 1},
+        {DeuceToSeven, "8s7h6d5c3s", "2s3h4d5c6s", 1},
+        {DeuceToSeven, "KsQhJdTc8s", "As2h3d4c5s", 1},
+        {DeuceToSeven, "9h8d7c5s3s", "2s3s4s5s7s", 1},
+        {DeuceToSeven, "2s2h3d4c5s", "AsKhQdJc9s", -1},
+    }
+    for _, test := range tests {
+        a := test.low(cardsOf(t, test.a))
+        b := test.low(cardsOf(t, test.b))
+        if got := sign(a.Compare(b)); got != test.want {
+            t.Errorf("%s against %s = %d, want %d", test.a, test.b, got, test.want)
+        }
+    }
+}
+
+func TestBestLow(t *testing.T) {
+    low := AceToFive(cardsOf(t, "KsAh2d3c4s5hQd"))
+    if low.Compare(AceToFive(cardsOf(t, "As2h3d4c5s"))) != 0 {
+        t.Errorf("best of seven %v", low)
+    }
+    if AceToFive(cardsOf(t, "As2h3d4c")) != nil {
+        t.Error("made a low from four cards")
+    }
+    if (*LowHand)(nil).Compare(low) >= 0 || low.Compare(nil) <= 0 {
+        t.Error("no low did not lose")
+    }
+}
+
+func TestQualifies(t *testing.T) {
+    tests := []struct {
+        cards string
+        ok bool
+    }{
+        {"As2h3d4c8s", true},
+        {"As2h3d4c9s", false},
+        {"As2h3d4cKs", false},
+        {"AsAh3d4c5s", false},
+        {"2s3h4d6c7s", true},
+    }
+    for _, test := range tests {
+        if got := AceToFive(cardsOf(t, test.cards)).Qualifies(6); got != test.ok {
+            t.Errorf("%s qualifies %v", test.cards, got)
+        }
+    }
+}
+
+func TestOmahaLow(t *testing.T) {
+    board := cardsOf(t, "3c4d8hQsJs")
+    low := OmahaLow(cardsOf(t, "As2hKdKc"), board)
+    if low.Compare(AceToFive(cardsOf(t, "As2h3c4d8h"))) != 0 {
+        t.Errorf("low %v", low)
+    }
+    // Exactly two hole cards play, so one low card in the hand is not enough.
+    if low := OmahaLow(cardsOf(t, "AsKdQcJh"), cardsOf(t, "2c3d4h5s6c")); low != nil {
+        t.Errorf("made %v from one low hole card", low)
+    }
+    // Without three low cards on the board there is no low.
+    if low := OmahaLow(cardsOf(t, "As2h3d4c"), cardsOf(t, "5c9dTsJhQc")); low != nil {
+        t.Errorf("made %v from two low board cards", low)
+    }
+}
@@ -0,0 +1,114 @@
+package poker
+
+import (
+    "errors"
+    "fmt"
+    "sort"
+)
+
+// How a match ends.
+const (
+    UntilLeave = iota  // plays on until a player leaves
+    FixedHands         // after Target hands
+    FirstTo            // once a player has Target points
+)
+
+// HandResult is what one hand of a match was worth to each seat.
+type HandResult struct {
+    Points []int
+    Royalties []int
+    Fouled []bool
+}
+
+// Match keeps the running score over a series of hands at one table.
+// Scores are by player id, so that a seat changing hands does not pass its
+// score on, and are sent to clients by seat rather than by id.
+type Match struct {
+    Length int
+    Target int
+    Scores map[string]int `json:"-"`
+    Results []*HandResult
+    Over bool
+}
+
+func NewMatch(length, target int) (*Match, error) {
+    switch length {
+        case UntilLeave:
+            target = 0
+        case FixedHands, FirstTo:
+            if target <= 0 {
+                return nil, errors.New("Match needs a positive target")
+            }
+        default:
+            return nil, errors.New("Unknown match length")
+    }
+    return &Match{Length: length, Target: target}, nil
+}
+
+func (m *Match) String() string {
+    switch m.Length {
+        case FixedHands:
+            if m.Target == 1 {
+                return "Match of 1 hand"
+            }
+            return fmt.Sprintf("Match of %d hands", m.Target)
+        case FirstTo:
+            return fmt.Sprintf("First to %d points", m.Target)
+    }
+    return "Open match"
+}
+
+// Record adds a finished hand's showdown to the scores of the players
+// seated at hands, and ends the match if it has run its length.
+func (m *Match) Record(hands []*ChineseHand, players []string) {
+    if m.Over {
+        return
+    }
+    s := NewShowdown(hands)
+    result := &HandResult{Points: s.Totals}
+    for i, hand := range hands {
+        if hand == nil {
+            result.Royalties = append(result.Royalties, 0)
+            result.Fouled = append(result.Fouled, false)
+        } else {
+            result.Royalties = append(result.Royalties, hand.Royalties())
+            result.Fouled = append(result.Fouled, hand.Fault())
+        }
+        if hand != nil && i < len(players) {
+            if m.Scores == nil {
+                m.Scores = make(map[string]int)
+            }
+            m.Scores[players[i]] += s.Totals[i]
+        }
+    }
+    m.Results = append(m.Results, result)
+    switch m.Length {
+        case FixedHands:
+            m.Over = len(m.Results) >= m.Target
+        case FirstTo:
+            for _, score := range m.Scores {
+                if score >= m.Target {
+                    m.Over = true
+                }
+            }
+    }
+}
+
+// End stops the match, e.g. because a player has left.
+func (m *Match) End() {
+    m.Over = true
+}
+
+// Leader returns the players with the highest score, in order of id.
+func (m *Match) Leader() []string {
+    best := make([]string, 0)
+    for id, score := range m.Scores {
+        if len(best) == 0 || score > m.Scores[best[0]] {
+            best = []string{id}
+        } else if score == m.Scores[best[0]] {
+            best = append(best, id)
+        }
+    }
+    sort.Strings(best)
+    return best
+}
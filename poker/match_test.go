@@ -0,0 +1,90 @@
+package poker
+
+import (
+    "fmt"
+    "testing"
+)
+
+// playOut finishes the hand, each card going to the first row with room.
+func playOut(t *testing.T, gs *GameState) {
+    for !gs.Finished() {
+        played := false
+        for pos := Back; pos <= Front && !played; pos++ {
+            played = gs.Play(gs.Players[gs.Turn], 0, pos) == nil
+        }
+        if !played {
+            t.Fatalf("no legal play for %v", gs.Showing)
+        }
+    }
+}
+
+func TestMatch(t *testing.T) {
+    gs, err := NewGame(0, StandardDeck, SeededSource(3))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if gs.Match, err = NewMatch(FixedHands, 2); err != nil {
+        t.Fatal(err)
+    }
+    for i := 0; i < 3; i++ {
+        if err := gs.Sit(fmt.Sprint("p", i), "", "", ""); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := gs.Start(); err != nil {
+        t.Fatal(err)
+    }
+    playOut(t, gs)
+    if len(gs.Match.Results) != 1 || gs.Match.Over {
+        t.Fatalf("after one hand: %d results, over %v", len(gs.Match.Results), gs.Match.Over)
+    }
+    first := make(map[string]int)
+    for id, score := range gs.Match.Scores {
+        first[id] = score
+    }
+    if err := gs.NewHand(SeededSource(4)); err != nil {
+        t.Fatal(err)
+    }
+    for i := 0; i < 3; i++ {
+        gs.Seed(fmt.Sprint("p", i), "")
+    }
+    if err := gs.Start(); err != nil {
+        t.Fatal(err)
+    }
+    playOut(t, gs)
+    if len(gs.Match.Results) != 2 || !gs.Match.Over {
+        t.Fatalf("after two hands: %d results, over %v", len(gs.Match.Results), gs.Match.Over)
+    }
+    total := 0
+    for i, id := range gs.Players {
+        score := gs.Match.Scores[id]
+        if score != first[id] + gs.Match.Results[1].Points[i] {
+            t.Errorf("player %s scored %d after %d and %d", id, score, first[id], gs.Match.Results[1].Points[i])
+        }
+        total += score
+    }
+    if total != 0 {
+        t.Errorf("scores %v do not sum to zero", gs.Match.Scores)
+    }
+    if gs.NewHand(SeededSource(5)) == nil {
+        t.Error("dealt a hand after the match was over")
+    }
+}
+
+func TestFirstTo(t *testing.T) {
+    m, err := NewMatch(FirstTo, 10)
+    if err != nil {
+        t.Fatal(err)
+    }
+    hands := []*ChineseHand{
+        chineseHand(t, "7s7h7d2c3s", "AsAh5d5c4s", "QsQh2d"),
+        chineseHand(t, "6s6h6d9cTs", "KsKhJdJc4h", "8s9hTd"),
+    }
+    m.Record(hands, []string{"a", "b"})
+    if !m.Over || m.Scores["a"] != 13 || len(m.Leader()) != 1 || m.Leader()[0] != "a" {
+        t.Errorf("scores %v, over %v", m.Scores, m.Over)
+    }
+    if _, err := NewMatch(FixedHands, 0); err == nil {
+        t.Error("accepted a match of no hands")
+    }
+}
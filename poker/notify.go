@@ -0,0 +1,234 @@
+package poker
+
+import (
+    "appengine"
+    "appengine/datastore"
+    "appengine/mail"
+    "bytes"
+    "crypto/tls"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net"
+    "net/http"
+    "net/smtp"
+    "net/url"
+    "time"
+)
+
+// DefaultMoveTime is how long a player has to move in a correspondence game
+// unless the table says otherwise.
+const DefaultMoveTime = 24 * time.Hour
+
+// SetAsync makes the game a correspondence game: each move has a long
+// deadline, and players are told when it is their turn rather than being
+// expected to keep the table open.
+func (gs *GameState) SetAsync(move time.Duration) error {
+    if move <= 0 {
+        move = DefaultMoveTime
+    }
+    if err := gs.SetTimer(move, 0); err != nil {
+        return err
+    }
+    gs.Async = true
+    return nil
+}
+
+// TurnNotice tells a player that it is their turn.
+type TurnNotice struct {
+    Game string
+    Player string
+    Name string
+    URL string
+    Deadline time.Time
+}
+
+// Notice is the notice for the player to act, or nil if no one is to act.
+// base is the address of the site, which the link to the game is made from.
+func (gs *GameState) Notice(base string) *TurnNotice {
+    if !gs.Started() || gs.Finished() || len(gs.Showing) == 0 {
+        return nil
+    }
+    return &TurnNotice{
+        Game: gs.Id(),
+        Player: gs.Players[gs.Turn],
+        Name: gs.PlayerNames[gs.Turn],
+        URL: base + "/game?id=" + gs.Id(),
+        Deadline: gs.Deadline,
+    }
+}
+
+func (n *TurnNotice) Subject() string {
+    return "Your turn at Chinese Poker"
+}
+
+func (n *TurnNotice) Body() string {
+    body := fmt.Sprintf("%s, it is your turn.\r\n\r\n%s\r\n", n.Name, n.URL)
+    if !n.Deadline.IsZero() {
+        body += fmt.Sprintf("\r\nPlay by %s or your cards will be placed for you.\r\n",
+            n.Deadline.UTC().Format("Jan 2 15:04 MST"))
+    }
+    return body
+}
+
+// A Notifier passes a TurnNotice on to the player.
+type Notifier interface {
+    Notify(n *TurnNotice) error
+}
+
+// MailNotifier mails the notice to the player, whose id is their address,
+// through App Engine's mail service.  Sender must be an address the app may
+// send from.
+type MailNotifier struct {
+    Context appengine.Context
+    Sender string
+    send func(c appengine.Context, msg *mail.Message) error  // mail.Send but in tests
+}
+
+func (m *MailNotifier) Notify(n *TurnNotice) error {
+    send := m.send
+    if send == nil {
+        send = mail.Send
+    }
+    return send(m.Context, &mail.Message{
+        Sender: m.Sender,
+        To: []string{n.Player},
+        Subject: n.Subject(),
+        Body: n.Body(),
+    })
+}
+
+// SMTPNotifier mails the notice to the player, whose id is their address,
+// through the SMTP server at Addr.  Dial defaults to a plain TCP dial; on
+// App Engine it should go through the socket API.
+type SMTPNotifier struct {
+    Addr string  // host:port of the mail server
+    From string
+    Auth smtp.Auth
+    Dial func(addr string) (net.Conn, error)
+}
+
+func (s *SMTPNotifier) Notify(n *TurnNotice) error {
+    dial := s.Dial
+    if dial == nil {
+        dial = func(addr string) (net.Conn, error) { return net.Dial("tcp", addr) }
+    }
+    host, _, err := net.SplitHostPort(s.Addr)
+    if err != nil {
+        return err
+    }
+    conn, err := dial(s.Addr)
+    if err != nil {
+        return err
+    }
+    c, err := smtp.NewClient(conn, host)
+    if err != nil {
+        conn.Close()
+        return err
+    }
+    defer c.Close()
+    if ok, _ := c.Extension("STARTTLS"); ok {
+        if err = c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+            return err
+        }
+    }
+    if s.Auth != nil {
+        if err = c.Auth(s.Auth); err != nil {
+            return err
+        }
+    }
+    if err = c.Mail(s.From); err != nil {
+        return err
+    }
+    if err = c.Rcpt(n.Player); err != nil {
+        return err
+    }
+    w, err := c.Data()
+    if err != nil {
+        return err
+    }
+    fmt.Fprintf(w, "From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s", s.From, n.Player, n.Subject(), n.Body())
+    if err = w.Close(); err != nil {
+        return err
+    }
+    return c.Quit()
+}
+
+// WebhookNotifier posts the notice as JSON to a URL.  Client defaults to
+// http.DefaultClient; on App Engine it should be a urlfetch client.
+type WebhookNotifier struct {
+    URL string
+    Client *http.Client
+}
+
+func (wh *WebhookNotifier) Notify(n *TurnNotice) error {
+    b, err := json.Marshal(n)
+    if err != nil {
+        return err
+    }
+    client := wh.Client
+    if client == nil {
+        client = http.DefaultClient
+    }
+    resp, err := client.Post(wh.URL, "application/json", bytes.NewReader(b))
+    if err != nil {
+        return err
+    }
+    resp.Body.Close()
+    if resp.StatusCode / 100 != 2 {
+        return errors.New("Webhook returned " + resp.Status)
+    }
+    return nil
+}
+
+// Notifiers sends the notice through each notifier in turn, returning the
+// first error once all have been tried.
+type Notifiers []Notifier
+
+func (ns Notifiers) Notify(n *TurnNotice) error {
+    var first error
+    for _, notifier := range ns {
+        if err := notifier.Notify(n); err != nil && first == nil {
+            first = err
+        }
+    }
+    return first
+}
+
+// NotifySettings is how a player wants to hear that it is their turn.
+type NotifySettings struct {
+    Player string
+    Email bool
+    Webhook string
+}
+
+// SetWebhook sets the URL notices are posted to, or clears it if u is
+// empty.  Only http and https URLs are taken.
+func (ns *NotifySettings) SetWebhook(u string) error {
+    if u == "" {
+        ns.Webhook = ""
+        return nil
+    }
+    parsed, err := url.Parse(u)
+    if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+        return errors.New("The webhook must be an http or https URL")
+    }
+    ns.Webhook = u
+    return nil
+}
+
+func LoadNotifySettings(player string, r *http.Request) (*NotifySettings, error) {
+    c := appengine.NewContext(r)
+    ns := &NotifySettings{Player: player}
+    err := datastore.Get(c, datastore.NewKey(c, "NotifySettings", player, 0, nil), ns)
+    if err != nil && err != datastore.ErrNoSuchEntity {
+        return nil, err
+    }
+    return ns, nil
+}
+
+func (ns *NotifySettings) Save(r *http.Request) error {
+    c := appengine.NewContext(r)
+    _, err := datastore.Put(c, datastore.NewKey(c, "NotifySettings", ns.Player, 0, nil), ns)
+    return err
+}
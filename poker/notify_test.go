@@ -0,0 +1,167 @@
+package poker
+
+import (
+    "appengine"
+    "appengine/mail"
+    "bufio"
+    "encoding/json"
+    "net"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "time"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+    got := make(chan *TurnNotice, 1)
+    server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        n := &TurnNotice{}
+        if err := json.NewDecoder(r.Body).Decode(n); err != nil {
+            t.Error(err)
+        }
+        got <- n
+    }))
+    defer server.Close()
+    sent := &TurnNotice{Game: "g", Player: "a@example.com", Name: "A", URL: "http://x/game?id=g"}
+    if err := (&WebhookNotifier{URL: server.URL}).Notify(sent); err != nil {
+        t.Fatal(err)
+    }
+    if n := <-got; *n != *sent {
+        t.Errorf("posted %+v, want %+v", n, sent)
+    }
+    failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        http.Error(w, "no", http.StatusInternalServerError)
+    }))
+    defer failing.Close()
+    if err := (&WebhookNotifier{URL: failing.URL}).Notify(sent); err == nil {
+        t.Errorf("no error from a failing webhook")
+    }
+}
+
+// fakeSMTP accepts one message and sends back its data.
+func fakeSMTP(t *testing.T) (string, chan string) {
+    l, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatal(err)
+    }
+    got := make(chan string, 1)
+    go func() {
+        defer l.Close()
+        conn, err := l.Accept()
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+        r := bufio.NewReader(conn)
+        reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+        reply("220 localhost")
+        var data []string
+        for inData := false; ; {
+            line, err := r.ReadString('\n')
+            if err != nil {
+                return
+            }
+            line = strings.TrimRight(line, "\r\n")
+            if inData {
+                if line == "." {
+                    inData = false
+                    got <- strings.Join(data, "\n")
+                    reply("250 ok")
+                } else {
+                    data = append(data, line)
+                }
+                continue
+            }
+            switch cmd := strings.ToUpper(strings.Fields(line + " x")[0]); cmd {
+                case "DATA":
+                    inData = true
+                    reply("354 go ahead")
+                case "QUIT":
+                    reply("221 bye")
+                    return
+                default:
+                    reply("250 ok")
+            }
+        }
+    }()
+    return l.Addr().String(), got
+}
+
+func TestSMTPNotifier(t *testing.T) {
+    addr, got := fakeSMTP(t)
+    n := &TurnNotice{Player: "a@example.com", Name: "A", URL: "http://x/game?id=g",
+        Deadline: time.Date(2020, 1, 2, 3, 4, 0, 0, time.UTC)}
+    if err := (&SMTPNotifier{Addr: addr, From: "poker@example.com"}).Notify(n); err != nil {
+        t.Fatal(err)
+    }
+    msg := <-got
+    for _, want := range []string{"To: a@example.com", "Subject: Your turn", "http://x/game?id=g", "Jan 2 03:04"} {
+        if !strings.Contains(msg, want) {
+            t.Errorf("message lacks %q:\n%s", want, msg)
+        }
+    }
+}
+
+func TestMailNotifier(t *testing.T) {
+    var sent *mail.Message
+    m := &MailNotifier{Sender: "poker@example.com", send: func(c appengine.Context, msg *mail.Message) error {
+        sent = msg
+        return nil
+    }}
+    n := &TurnNotice{Player: "a@example.com", Name: "A", URL: "http://x/game?id=g",
+        Deadline: time.Date(2020, 1, 2, 3, 4, 0, 0, time.UTC)}
+    if err := m.Notify(n); err != nil {
+        t.Fatal(err)
+    }
+    if sent == nil || sent.Sender != "poker@example.com" || len(sent.To) != 1 || sent.To[0] != "a@example.com" ||
+        !strings.HasPrefix(sent.Subject, "Your turn") {
+        t.Fatalf("sent %+v", sent)
+    }
+    for _, want := range []string{"http://x/game?id=g", "Jan 2 03:04"} {
+        if !strings.Contains(sent.Body, want) {
+            t.Errorf("message lacks %q:\n%s", want, sent.Body)
+        }
+    }
+}
+
+func TestSetWebhook(t *testing.T) {
+    ns := &NotifySettings{}
+    for _, ok := range []string{"https://example.com/hook", "http://example.com:8080/x?y=1", ""} {
+        if err := ns.SetWebhook(ok); err != nil || ns.Webhook != ok {
+            t.Errorf("SetWebhook(%q): %v", ok, err)
+        }
+    }
+    ns.Webhook = "https://example.com/hook"
+    for _, bad := range []string{"javascript:alert(1)", "file:///etc/passwd", "ftp://example.com/", "example.com/hook", "http://"} {
+        if err := ns.SetWebhook(bad); err == nil {
+            t.Errorf("took %q", bad)
+        }
+    }
+    if ns.Webhook != "https://example.com/hook" {
+        t.Errorf("a bad URL replaced the webhook: %q", ns.Webhook)
+    }
+}
+
+func TestNotice(t *testing.T) {
+    gs, err := NewGame(0, StandardDeck, SeededSource(9))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if err := gs.SetAsync(0); err != nil {
+        t.Fatal(err)
+    }
+    gs.Sit("a", "A", "", "")
+    gs.Sit("b", "B", "", "")
+    if gs.Notice("") != nil {
+        t.Errorf("notice before the start")
+    }
+    gs.Start()
+    n := gs.Notice("http://x")
+    if n == nil || n.Player != "a" || n.Deadline.Sub(gs.TurnStart) != DefaultMoveTime {
+        t.Errorf("notice %+v", n)
+    }
+    if gd := gs.data(nil); gd.ToAct != "a" || len(gd.Seats) != 2 || !gd.Async {
+        t.Errorf("stored %+v", gd)
+    }
+}
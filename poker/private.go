@@ -0,0 +1,174 @@
+package poker
+
+import (
+    "appengine"
+    "appengine/datastore"
+    "errors"
+    "net/http"
+    "strings"
+)
+
+// Invite codes avoid letters and digits that are easy to confuse.
+const (
+    inviteAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+    inviteLength = 6
+)
+
+func NewInviteCode(src RandSource) (string, error) {
+    code := make([]byte, inviteLength)
+    for i := range code {
+        n, err := intn(src, len(inviteAlphabet))
+        if err != nil {
+            return "", err
+        }
+        code[i] = inviteAlphabet[n]
+    }
+    return string(code), nil
+}
+
+// inviteTries is how many codes MakePrivate draws before giving up.  With
+// 32^6 codes a clash is rare, and several in a row mean something is wrong.
+const inviteTries = 10
+
+// MakePrivate hides the table from the lobby and gives it an invite code
+// that players other than the owner need in order to sit.  taken says
+// whether another table already has a code; a taken code is drawn again.
+func (gs *GameState) MakePrivate(taken func(code string) (bool, error)) error {
+    for i := 0; i < inviteTries; i++ {
+        code, err := NewInviteCode(gs.source())
+        if err != nil {
+            return err
+        }
+        used, err := taken(code)
+        if err != nil {
+            return err
+        }
+        if !used {
+            gs.Private = true
+            gs.InviteCode = code
+            return nil
+        }
+    }
+    return errors.New("Could not find an unused invite code")
+}
+
+func (gs *GameState) IsOwner(id string) bool {
+    return gs.Owner != "" && gs.Owner == id
+}
+
+func (gs *GameState) reserved(id string) bool {
+    for _, r := range gs.Reservations {
+        if r == id {
+            return true
+        }
+    }
+    return false
+}
+
+// openReservations counts reserved players who have not sat down yet.
+func (gs *GameState) openReservations() int {
+    n := 0
+    for _, r := range gs.Reservations {
+        if !gs.InGame(r) {
+            n++
+        }
+    }
+    return n
+}
+
+// mayJoin checks the table's restrictions on a player sitting down.
+func (gs *GameState) mayJoin(id, code string) error {
+    if gs.InGame(id) {
+        return ErrAlreadySitting
+    }
+    if gs.reserved(id) {
+        return nil
+    }
+    if gs.Locked {
+        return ErrLocked
+    }
+    if gs.Private && !gs.IsOwner(id) && !strings.EqualFold(strings.TrimSpace(code), gs.InviteCode) {
+        return ErrNeedsCode
+    }
+    if gs.occupied() + gs.openReservations() >= gs.MaxPlayers() {
+        return ErrFull
+    }
+    return nil
+}
+
+func (gs *GameState) checkOwner(id string) error {
+    if !gs.IsOwner(id) {
+        return ErrNotOwner
+    }
+    return nil
+}
+
+// Reserve holds a seat for a player, who may then sit without the invite
+// code even once the table is locked.
+func (gs *GameState) Reserve(by, player string) error {
+    if err := gs.checkOwner(by); err != nil {
+        return err
+    }
+    if player == "" || gs.reserved(player) {
+        return nil
+    }
+    if !gs.InGame(player) && gs.occupied() + gs.openReservations() >= gs.MaxPlayers() {
+        return errors.New("No seats left to reserve")
+    }
+    gs.Reservations = append(gs.Reservations, player)
+    return nil
+}
+
+// Kick removes the player in seat between hands.
+func (gs *GameState) Kick(by string, seat int) error {
+    if err := gs.checkOwner(by); err != nil {
+        return err
+    }
+    if !gs.betweenHands() {
+        return ErrHandInProgress
+    }
+    if seat < 0 || seat >= len(gs.Players) || gs.Players[seat] == "" {
+        return errors.New("No such seat")
+    }
+    // Without their reservation they need the code, and the owner's leave,
+    // to come back.
+    gs.Reservations = without(gs.Reservations, gs.Players[seat])
+    gs.freeSeat(seat)
+    return nil
+}
+
+// Lock stops anyone without a reservation from sitting down.
+func (gs *GameState) Lock(by string, locked bool) error {
+    if err := gs.checkOwner(by); err != nil {
+        return err
+    }
+    gs.Locked = locked
+    return nil
+}
+
+func findInvite(code string, r *http.Request) ([]*datastore.Key, error) {
+    c := appengine.NewContext(r)
+    code = strings.ToUpper(strings.TrimSpace(code))
+    return datastore.NewQuery("GameState").Filter("InviteCode =", code).KeysOnly().Limit(1).GetAll(c, nil)
+}
+
+// FindByInvite returns the id of the game with the invite code.
+func FindByInvite(code string, r *http.Request) (string, error) {
+    keys, err := findInvite(code, r)
+    if err != nil {
+        return "", err
+    }
+    if len(keys) == 0 {
+        return "", errors.New("No table has that invite code")
+    }
+    return keys[0].Encode(), nil
+}
+
+// InviteTaken returns a check, for MakePrivate, of whether a table already
+// has a code.
+func InviteTaken(r *http.Request) func(code string) (bool, error) {
+    return func(code string) (bool, error) {
+        keys, err := findInvite(code, r)
+        return len(keys) > 0, err
+    }
+}
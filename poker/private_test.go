@@ -0,0 +1,99 @@
+package poker
+
+import (
+    "strings"
+    "testing"
+)
+
+func TestInviteCode(t *testing.T) {
+    code, err := NewInviteCode(SeededSource(1))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if len(code) != inviteLength {
+        t.Errorf("code %q", code)
+    }
+    for _, c := range code {
+        if !strings.ContainsRune(inviteAlphabet, c) {
+            t.Errorf("code %q uses %q", code, c)
+        }
+    }
+}
+
+func TestPrivateTable(t *testing.T) {
+    gs, err := NewGame(0, StandardDeck, SeededSource(1))
+    if err != nil {
+        t.Fatal(err)
+    }
+    gs.Owner = "owner"
+    // Every code is taken.
+    if err := gs.MakePrivate(func(string) (bool, error) { return true, nil }); err == nil || gs.Private {
+        t.Errorf("made private with a taken code %q", gs.InviteCode)
+    }
+    // The first two codes drawn are taken.
+    var tried []string
+    taken := func(code string) (bool, error) {
+        tried = append(tried, code)
+        return len(tried) <= 2, nil
+    }
+    if err := gs.MakePrivate(taken); err != nil {
+        t.Fatal(err)
+    }
+    if len(tried) != 3 || gs.InviteCode != tried[2] {
+        t.Errorf("drew %v and kept %s", tried, gs.InviteCode)
+    }
+    if err := gs.Sit("owner", "O", "", ""); err != nil {
+        t.Errorf("owner could not sit: %v", err)
+    }
+    if err := gs.Sit("a", "A", "", "WRONG1"); err == nil {
+        t.Errorf("sat with the wrong code")
+    }
+    if err := gs.Sit("a", "A", "", strings.ToLower(gs.InviteCode)); err != nil {
+        t.Errorf("could not sit with the code: %v", err)
+    }
+    if err := gs.Sit("a", "A", "", gs.InviteCode); err == nil {
+        t.Errorf("sat twice")
+    }
+    if err := gs.Reserve("a", "b"); err == nil {
+        t.Errorf("a player who is not the owner reserved a seat")
+    }
+    if err := gs.Reserve("owner", "b"); err != nil {
+        t.Fatal(err)
+    }
+    if err := gs.Sit("c", "C", "", gs.InviteCode); err != nil {
+        t.Fatal(err)
+    }
+    // The last seat is held for b.
+    if err := gs.Sit("d", "D", "", gs.InviteCode); err == nil {
+        t.Errorf("took a reserved seat")
+    }
+    if err := gs.Lock("owner", true); err != nil {
+        t.Fatal(err)
+    }
+    if err := gs.Sit("b", "B", "", ""); err != nil {
+        t.Errorf("reserved player could not sit at a locked table: %v", err)
+    }
+    if err := gs.Kick("a", 0); err == nil {
+        t.Errorf("a player who is not the owner kicked")
+    }
+    if err := gs.Kick("owner", 1); err != nil {
+        t.Fatal(err)
+    }
+    // A kicked player loses their reservation.
+    if err := gs.Kick("owner", 2); err != nil || gs.reserved("b") {
+        t.Errorf("kicked b: %v, reservations %v", err, gs.Reservations)
+    }
+    if err := gs.Sit("b", "B", "", ""); err != ErrLocked {
+        t.Errorf("kicked player sat again: %v", err)
+    }
+    if gs.InGame("a") || len(gs.Hands) != 2 || gs.PlayerNames[1] != "C" {
+        t.Errorf("after the kick: %v %v", gs.Players, gs.PlayerNames)
+    }
+    if err := gs.Sit("a", "A", "", gs.InviteCode); err == nil {
+        t.Errorf("sat at a locked table")
+    }
+    gs.Start()
+    if err := gs.Kick("owner", 0); err == nil {
+        t.Errorf("kicked after the start")
+    }
+}
@@ -0,0 +1,64 @@
+package poker
+
+import (
+    crand "crypto/rand"
+    "encoding/binary"
+    "math/big"
+    "math/rand"
+)
+
+// RandSource supplies the randomness behind shuffles, server seeds and
+// simulations.  Any io.Reader will do; the same bytes always give the same
+// results.
+type RandSource interface {
+    Read(p []byte) (n int, err error)
+}
+
+// CryptoSource draws from the operating system's secure generator.  Errors
+// are passed on to the caller rather than papered over.
+var CryptoSource RandSource = crand.Reader
+
+// SeededSource returns a reproducible source for tests and simulations.  It
+// is not suitable for dealing real games.
+func SeededSource(seed int64) RandSource {
+    return rand.New(rand.NewSource(seed))
+}
+
+func intn(src RandSource, n int) (int, error) {
+    i, err := crand.Int(src, big.NewInt(int64(n)))
+    if err != nil {
+        return 0, err
+    }
+    return int(i.Int64()), nil
+}
+
+// newRand derives a math/rand generator from src, for the hot loops of
+// simulations where reading src for every number would be too slow.
+func newRand(src RandSource) (*rand.Rand, error) {
+    b := make([]byte, 8)
+    if _, err := src.Read(b); err != nil {
+        return nil, err
+    }
+    return rand.New(rand.NewSource(int64(binary.BigEndian.Uint64(b)))), nil
+}
+
+// Shuffler decides the order of the deck when a hand starts.
+type Shuffler interface {
+    Shuffle(spec DeckSpec, serverSeed string, clientSeeds []string) Deck
+}
+
+// FairShuffler deals the provably fair order given by the seeds.  Games use
+// it unless told otherwise.
+type FairShuffler struct{}
+
+func (FairShuffler) Shuffle(spec DeckSpec, serverSeed string, clientSeeds []string) Deck {
+    return spec.SeededShuffle(serverSeed, clientSeeds)
+}
+
+// PresetDeck deals its cards in the order given, ignoring the seeds, so that
+// scripted scenarios can be played out.  The deals will not verify.
+type PresetDeck Deck
+
+func (pd PresetDeck) Shuffle(spec DeckSpec, serverSeed string, clientSeeds []string) Deck {
+    return append(Deck{}, pd...)
+}
@@ -0,0 +1,89 @@
+package poker
+
+import (
+    "bytes"
+    "testing"
+)
+
+func TestSeededSource(t *testing.T) {
+    read := func(seed int64) []byte {
+        b := make([]byte, 32)
+        SeededSource(seed).Read(b)
+        return b
+    }
+    if !bytes.Equal(read(1), read(1)) {
+        t.Error("the same seed gave different bytes")
+    }
+    if bytes.Equal(read(1), read(2)) {
+        t.Error("different seeds gave the same bytes")
+    }
+    a, _ := NewGame(0, StandardDeck, SeededSource(1))
+    b, _ := NewGame(0, StandardDeck, SeededSource(1))
+    if a.ServerSeed != b.ServerSeed {
+        t.Errorf("server seeds %s and %s from one seed", a.ServerSeed, b.ServerSeed)
+    }
+}
+
+func TestFairShuffler(t *testing.T) {
+    spec := DeckSpec{Decks: 2, Jokers: 1}
+    d := FairShuffler{}.Shuffle(spec, "server", []string{"a", "b"})
+    again := FairShuffler{}.Shuffle(spec, "server", []string{"a", "b"})
+    other := FairShuffler{}.Shuffle(spec, "server", []string{"a", "c"})
+    same, moved := true, false
+    for i := range d {
+        same = same && d[i] == again[i]
+        moved = moved || d[i] != other[i]
+    }
+    if !same || !moved {
+        t.Errorf("repeated %v, changed by a client seed %v", same, moved)
+    }
+    counts := map[Card]int{}
+    for _, c := range d {
+        counts[c]++
+    }
+    for _, c := range spec.Ordered() {
+        counts[c]--
+    }
+    for c, n := range counts {
+        if n != 0 {
+            t.Errorf("%s is off by %d", c.Code(), n)
+        }
+    }
+    f := &Fairness{Spec: spec, Commitment: Commitment("server"), ClientSeeds: []string{"a", "b"}, ServerSeed: "server", Dealt: d[:10]}
+    if err := f.Verify(); err != nil {
+        t.Error(err)
+    }
+    f.ClientSeeds = []string{"a", "c"}
+    if f.Verify() == nil {
+        t.Error("verified against the wrong client seeds")
+    }
+}
+
+func TestPresetDeck(t *testing.T) {
+    gs, err := NewGame(0, StandardDeck, SeededSource(3))
+    if err != nil {
+        t.Fatal(err)
+    }
+    preset := PresetDeck(StandardDeck.Ordered())
+    gs.SetShuffler(preset)
+    gs.Sit("a", "A", "", "")
+    gs.Sit("b", "B", "", "")
+    if err := gs.Start(); err != nil {
+        t.Fatal(err)
+    }
+    for i, c := range gs.Showing {
+        if c != preset[i] {
+            t.Fatalf("dealt %v, want the top of %v", gs.Showing, preset[:len(gs.Showing)])
+        }
+    }
+    // Playing from the deck leaves the preset as it was.
+    gs.Deck[0] = gs.Deck[1]
+    if preset[0] == preset[1] {
+        t.Error("the game dealt from the preset itself")
+    }
+    playOut(t, gs)
+    f := gs.ClientState("a").Fairness
+    if f == nil || f.Verify() == nil {
+        t.Errorf("a preset deal verified: %+v", f)
+    }
+}
@@ -0,0 +1,346 @@
+package poker
+
+import (
+    "errors"
+    "fmt"
+    "math/rand"
+    "sort"
+    "strconv"
+    "strings"
+)
+
+// Combo is one specific pair of Hold'em hole cards, higher rank first, with
+// the weight it carries in its range.
+type Combo struct {
+    Cards [2]Card
+    Weight float64
+}
+
+func (c Combo) Hole() []Card {
+    return []Card{c.Cards[0], c.Cards[1]}
+}
+
+func (c Combo) String() string {
+    return c.Cards[0].Code() + c.Cards[1].Code()
+}
+
+type Range []Combo
+
+func newCombo(a, b Card, weight float64) Combo {
+    if a.Rank() < b.Rank() || (a.Rank() == b.Rank() && a.Suit() > b.Suit()) {
+        a, b = b, a
+    }
+    return Combo{[2]Card{a, b}, weight}
+}
+
+func pairCombos(r Rank, weight float64) []Combo {
+    combos := make([]Combo, 0, 6)
+    for s1 := Suit(0); s1 < 4; s1++ {
+        for s2 := s1 + 1; s2 < 4; s2++ {
+            combos = append(combos, newCombo(NewCard(r, s1), NewCard(r, s2), weight))
+        }
+    }
+    return combos
+}
+
+func unpairedCombos(hi, lo Rank, suited, offsuit bool, weight float64) []Combo {
+    combos := make([]Combo, 0, 16)
+    for s1 := Suit(0); s1 < 4; s1++ {
+        for s2 := Suit(0); s2 < 4; s2++ {
+            if (s1 == s2 && suited) || (s1 != s2 && offsuit) {
+                combos = append(combos, newCombo(NewCard(hi, s1), NewCard(lo, s2), weight))
+            }
+        }
+    }
+    return combos
+}
+
+// rangeHand is a parsed hand class such as "AKs" or "77".
+type rangeHand struct {
+    hi, lo Rank
+    suited, offsuit bool
+}
+
+func parseRangeHand(s string) (*rangeHand, error) {
+    if len(s) != 2 && len(s) != 3 {
+        return nil, fmt.Errorf("Bad hand \"%s\"", s)
+    }
+    hi, err := ParseRank(s[0])
+    if err != nil {
+        return nil, err
+    }
+    lo, err := ParseRank(s[1])
+    if err != nil {
+        return nil, err
+    }
+    if hi < lo {
+        hi, lo = lo, hi
+    }
+    rh := &rangeHand{hi, lo, true, true}
+    if len(s) == 3 {
+        switch s[2] {
+            case 's', 'S':
+                rh.offsuit = false
+            case 'o', 'O':
+                rh.suited = false
+            default:
+                return nil, fmt.Errorf("Bad hand \"%s\"", s)
+        }
+    }
+    if hi == lo && len(s) == 3 {
+        return nil, fmt.Errorf("Pairs cannot be suited or offsuit: \"%s\"", s)
+    }
+    return rh, nil
+}
+
+func (rh *rangeHand) combos(weight float64) []Combo {
+    if rh.hi == rh.lo {
+        return pairCombos(rh.hi, weight)
+    }
+    return unpairedCombos(rh.hi, rh.lo, rh.suited, rh.offsuit, weight)
+}
+
+// parseRangeToken expands a single comma separated part of a range.
+func parseRangeToken(tok string) ([]Combo, error) {
+    weight := 1.0
+    if i := strings.Index(tok, ":"); i >= 0 {
+        w, err := strconv.ParseFloat(tok[i+1:], 64)
+        if err != nil || w < 0 || w > 1 {
+            return nil, fmt.Errorf("Bad weight in \"%s\"", tok)
+        }
+        weight = w
+        tok = tok[:i]
+    }
+    if len(tok) == 4 {
+        if cards, err := ParseCards(tok); err == nil {
+            if cards[0] == cards[1] {
+                return nil, fmt.Errorf("Bad hand \"%s\"", tok)
+            }
+            return []Combo{newCombo(cards[0], cards[1], weight)}, nil
+        }
+    }
+    combos := make([]Combo, 0)
+    if i := strings.Index(tok, "-"); i >= 0 {
+        from, err := parseRangeHand(tok[:i])
+        if err != nil {
+            return nil, err
+        }
+        to, err := parseRangeHand(tok[i+1:])
+        if err != nil {
+            return nil, err
+        }
+        if from.suited != to.suited || from.offsuit != to.offsuit {
+            return nil, fmt.Errorf("Mismatched ends in \"%s\"", tok)
+        }
+        if from.hi == from.lo && to.hi == to.lo {
+            // 22-55
+            lo, hi := from.hi, to.hi
+            if lo > hi {
+                lo, hi = hi, lo
+            }
+            for r := lo; r <= hi; r++ {
+                combos = append(combos, pairCombos(r, weight)...)
+            }
+            return combos, nil
+        }
+        if from.hi != to.hi || from.hi == from.lo || to.hi == to.lo {
+            return nil, fmt.Errorf("Bad span \"%s\"", tok)
+        }
+        // A5s-A2s
+        lo, hi := from.lo, to.lo
+        if lo > hi {
+            lo, hi = hi, lo
+        }
+        for r := lo; r <= hi; r++ {
+            combos = append(combos, unpairedCombos(from.hi, r, from.suited, from.offsuit, weight)...)
+        }
+        return combos, nil
+    }
+    plus := strings.HasSuffix(tok, "+")
+    rh, err := parseRangeHand(strings.TrimSuffix(tok, "+"))
+    if err != nil {
+        return nil, err
+    }
+    if !plus {
+        return rh.combos(weight), nil
+    }
+    if rh.hi == rh.lo {
+        // QQ+
+        for r := rh.hi; r <= 12; r++ {
+            combos = append(combos, pairCombos(r, weight)...)
+        }
+        return combos, nil
+    }
+    // KTo+ raises the kicker up to one below the top card.
+    for r := rh.lo; r < rh.hi; r++ {
+        combos = append(combos, unpairedCombos(rh.hi, r, rh.suited, rh.offsuit, weight)...)
+    }
+    return combos, nil
+}
+
+// ParseRange reads standard range notation, e.g. "QQ+, AKs, A5s-A2s, KTo+".
+// Specific combos ("AsKs") and weights ("AKo:0.5") are also accepted.  A
+// combo listed more than once keeps the last weight given for it.
+func ParseRange(s string) (Range, error) {
+    index := make(map[[2]Card]int)
+    rg := make(Range, 0)
+    for _, tok := range strings.Split(s, ",") {
+        tok = strings.TrimSpace(tok)
+        if tok == "" {
+            continue
+        }
+        combos, err := parseRangeToken(tok)
+        if err != nil {
+            return nil, err
+        }
+        for _, combo := range combos {
+            if i, ok := index[combo.Cards]; ok {
+                rg[i].Weight = combo.Weight
+                continue
+            }
+            index[combo.Cards] = len(rg)
+            rg = append(rg, combo)
+        }
+    }
+    return rg, nil
+}
+
+// Without returns the combos of rg that use none of the dead cards and carry
+// some weight.
+func (rg Range) Without(dead []Card) Range {
+    result := make(Range, 0, len(rg))
+    for _, combo := range rg {
+        if combo.Weight > 0 && !combo.uses(dead) {
+            result = append(result, combo)
+        }
+    }
+    return result
+}
+
+func (c Combo) uses(cards []Card) bool {
+    for _, card := range cards {
+        if card == c.Cards[0] || card == c.Cards[1] {
+            return true
+        }
+    }
+    return false
+}
+
+func (c Combo) set() CardSet {
+    return bit(c.Cards[0]) | bit(c.Cards[1])
+}
+
+func (rg Range) Weight() float64 {
+    w := 0.0
+    for _, combo := range rg {
+        w += combo.Weight
+    }
+    return w
+}
+
+// sampler draws combos from a range in proportion to their weights.
+type sampler struct {
+    rg Range
+    cumulative []float64
+}
+
+func newSampler(rg Range) *sampler {
+    s := &sampler{rg, make([]float64, len(rg))}
+    total := 0.0
+    for i, combo := range rg {
+        total += combo.Weight
+        s.cumulative[i] = total
+    }
+    return s
+}
+
+func (s *sampler) draw(rnd *rand.Rand) Combo {
+    x := rnd.Float64() * s.cumulative[len(s.cumulative)-1]
+    return s.rg[sort.SearchFloat64s(s.cumulative, x)]
+}
+
+// RangeEquity computes Hold'em equity between ranges, dealing from the deck
+// described by spec, which may be short but must be a single deck without
+// jokers, as range notation has no way to name the extra cards.  Combos that clash with the board, the dead cards or
+// each other are removed.  The matchups and boards are enumerated when there
+// are at most equityExactLimit of them, weighting each by its combos'
+// weights; otherwise trials deals are sampled using src.
+func RangeEquity(spec DeckSpec, ranges []Range, board, dead []Card, trials int, src RandSource) (*EquityResult, error) {
+    if len(ranges) < 2 {
+        return nil, errors.New("Need at least two ranges")
+    }
+    if len(board) > 5 {
+        return nil, errors.New("Board has more than five cards")
+    }
+    if spec.decks() > 1 || spec.Jokers > 0 {
+        return nil, errors.New("Range equity needs a single deck without jokers")
+    }
+    used := append(append([]Card{}, board...), dead...)
+    if err := spec.check(used); err != nil {
+        return nil, err
+    }
+    // Cards left out of a short deck are as good as dead.
+    used = append(used, remaining(NewOrderedDeck(), spec.Ordered())...)
+    // Range equity is dealt from one deck, so every card here fits a set.
+    usedSet, _ := NewCardSet(used)
+    live := make([]Range, len(ranges))
+    matchups := 1.0
+    for i, rg := range ranges {
+        live[i] = rg.Without(used)
+        if len(live[i]) == 0 {
+            return nil, fmt.Errorf("Range %d has no live combos", i + 1)
+        }
+        matchups *= float64(len(live[i]))
+    }
+    need := 5 - len(board)
+    if matchups * choose(52 - len(used) - 2 * len(ranges), need) <= equityExactLimit {
+        deals := make([]*deal, 0)
+        holes := make([][]Card, len(live))
+        var rec func(i int, weight float64, used CardSet)
+        rec = func(i int, weight float64, used CardSet) {
+            if i == len(live) {
+                h := append([][]Card{}, holes...)
+                combinations(allCards.Minus(used).Cards(), need, func(chosen, _ []Card) {
+                    b := append(append([]Card{}, board...), chosen...)
+                    deals = append(deals, &deal{h, b, weight})
+                })
+                return
+            }
+            for _, combo := range live[i] {
+                if used & combo.set() == 0 {
+                    holes[i] = combo.Hole()
+                    rec(i + 1, weight * combo.Weight, used | combo.set())
+                }
+            }
+        }
+        rec(0, 1, usedSet)
+        if len(deals) == 0 {
+            return nil, errors.New("Ranges have no compatible combos")
+        }
+        return run(Holdem, spec, len(ranges), deals, 0, nil, nil)
+    }
+    samplers := make([]*sampler, len(live))
+    for i, rg := range live {
+        samplers[i] = newSampler(rg)
+    }
+    result, err := run(Holdem, spec, len(ranges), nil, trials, src, func(rnd *rand.Rand) *deal {
+        holes := make([][]Card, len(samplers))
+        taken := usedSet
+        for i, s := range samplers {
+            combo := s.draw(rnd)
+            if taken & combo.set() != 0 {
+                return nil
+            }
+            holes[i] = combo.Hole()
+            taken |= combo.set()
+        }
+        return &deal{holes, completeBoard(rnd, board, allCards.Minus(taken).Cards()), 1}
+    })
+    if err != nil {
+        return nil, err
+    }
+    if result.Trials == 0 {
+        return nil, errors.New("Ranges have no compatible combos")
+    }
+    return result, nil
+}
@@ -0,0 +1,87 @@
+package poker
+
+import (
+    "testing"
+)
+
+func TestParseRange(t *testing.T) {
+    tests := []struct {
+        in string
+        combos int
+        weight float64
+    }{
+        {"AA", 6, 6},
+        {"AKs", 4, 4},
+        {"AKo", 12, 12},
+        {"AK", 16, 16},
+        {"KA", 16, 16},
+        {"QQ+", 18, 18},
+        {"22-44", 18, 18},
+        {"A5s-A2s", 16, 16},
+        {"KTo+", 36, 36},
+        {"AsKs", 1, 1},
+        {"AKo:0.5", 12, 6},
+        // A combo listed twice keeps its last weight.
+        {"AA, AsAh:0.25", 6, 5.25},
+        {" QQ+ ,, AKs ", 22, 22},
+    }
+    for _, test := range tests {
+        rg, err := ParseRange(test.in)
+        if err != nil {
+            t.Errorf("ParseRange(%q): %v", test.in, err)
+            continue
+        }
+        if len(rg) != test.combos || rg.Weight() != test.weight {
+            t.Errorf("ParseRange(%q) has %d combos weighing %v, want %d weighing %v",
+                test.in, len(rg), rg.Weight(), test.combos, test.weight)
+        }
+    }
+    for _, bad := range []string{"AAs", "AKx", "AK:2", "AK:x", "AsAs", "AKs-QJs", "AKs-AQo", "A", "AKQJ"} {
+        if _, err := ParseRange(bad); err == nil {
+            t.Errorf("ParseRange(%q) succeeded", bad)
+        }
+    }
+}
+
+func TestRangeWithout(t *testing.T) {
+    rg, _ := ParseRange("AA, KK")
+    if n := len(rg.Without(cardsOf(t, "As"))); n != 9 {
+        t.Errorf("%d combos left without the ace of spades", n)
+    }
+}
+
+func TestRangeEquity(t *testing.T) {
+    aces, _ := ParseRange("AA")
+    kings, _ := ParseRange("KK")
+    board := cardsOf(t, "2c7d9hJcQd")
+    // On a dry river the aces always win, whichever combos are dealt.
+    er, err := RangeEquity(StandardDeck, []Range{aces, kings}, board, nil, 1000, SeededSource(1))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if !er.Exact || er.Trials != 36 || er.Equity[0] != 100 {
+        t.Errorf("river range equity %+v", er)
+    }
+    // One combo cannot be dealt to two players.
+    one, _ := ParseRange("AsAh")
+    if _, err := RangeEquity(StandardDeck, []Range{one, one}, nil, nil, 1000, SeededSource(1)); err == nil {
+        t.Error("dealt the same combo to both players")
+    }
+    // Preflop there are too many boards to deal them all.
+    er, err = RangeEquity(StandardDeck, []Range{aces, kings}, nil, nil, 4000, SeededSource(2))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if er.Exact || er.Trials != 4000 || er.Equity[0] < 78 || er.Equity[0] > 86 {
+        t.Errorf("preflop range equity %+v", er)
+    }
+    // Combos that clash with the board are dropped, leaving none.
+    if _, err := RangeEquity(StandardDeck, []Range{one, kings}, cardsOf(t, "AsAh2c"), nil, 100, SeededSource(1)); err == nil {
+        t.Error("dealt a combo using board cards")
+    }
+    for _, spec := range []DeckSpec{{Decks: 2}, {Jokers: 1}} {
+        if _, err := RangeEquity(spec, []Range{aces, kings}, nil, nil, 100, SeededSource(1)); err == nil {
+            t.Errorf("range equity from %+v", spec)
+        }
+    }
+}
@@ -0,0 +1,198 @@
+package poker
+
+// A seat whose player has stood up keeps its place, with an empty id, so
+// that a match's scores stay with their seats; the next player to sit takes
+// it over.  Empty seats and players sitting out are dealt no hand, and a nil
+// hand is skipped by NextTurn and left out of the showdown.
+
+func (gs *GameState) seatOf(id string) int {
+    for i, p := range gs.Players {
+        if id != "" && p == id {
+            return i
+        }
+    }
+    return -1
+}
+
+func (gs *GameState) SittingOut(seat int) bool {
+    return seat < len(gs.Out) && gs.Out[seat]
+}
+
+// seated is whether the seat's player is to be dealt the next hand.
+func (gs *GameState) seated(seat int) bool {
+    return gs.Players[seat] != "" && !gs.SittingOut(seat) && !gs.busted(seat)
+}
+
+// occupied counts the seats with a player in them.
+func (gs *GameState) occupied() int {
+    n := 0
+    for _, p := range gs.Players {
+        if p != "" {
+            n++
+        }
+    }
+    return n
+}
+
+// betweenHands is whether no hand is being played.
+func (gs *GameState) betweenHands() bool {
+    return !gs.Started() || gs.Finished()
+}
+
+// takeSeat puts a player in the first empty seat, or a new one.  The seed
+// counts for the next hand, as the server's commitment to it is already out.
+func (gs *GameState) takeSeat(id, name, seed string) {
+    for len(gs.Out) < len(gs.Players) {
+        gs.Out = append(gs.Out, false)
+    }
+    for i, p := range gs.Players {
+        if p == "" {
+            gs.Players[i], gs.PlayerNames[i], gs.Out[i] = id, name, false
+            gs.Hands[i] = &ChineseHand{Low: gs.Spec.Low}
+            if i < len(gs.Banks) {
+                gs.Banks[i] = gs.TimeBank
+            }
+            if i < len(gs.Stacks) {
+                gs.Stacks[i] = gs.Stakes.BuyIn
+            }
+            gs.setSeed(i, seed)
+            return
+        }
+    }
+    gs.Players = append(gs.Players, id)
+    gs.PlayerNames = append(gs.PlayerNames, name)
+    gs.ClientSeeds = append(gs.ClientSeeds, "")
+    gs.Out = append(gs.Out, false)
+    gs.Hands = append(gs.Hands, &ChineseHand{Low: gs.Spec.Low})
+    gs.setSeed(len(gs.Players) - 1, seed)
+}
+
+// freeSeat empties a seat between hands.  Before the first hand there is
+// nothing to keep the seat's place for, so it is taken out altogether.  A
+// finished hand stays on show, under the old name, until the next is dealt.
+// An open match ends when anyone gives up their seat, however they go.
+func (gs *GameState) freeSeat(seat int) {
+    if gs.Match != nil && gs.Match.Length == UntilLeave && gs.HandsPlayed > 0 {
+        gs.Match.End()
+    }
+    if gs.HandsPlayed == 0 && !gs.Started() {
+        gs.Players = append(gs.Players[:seat], gs.Players[seat+1:]...)
+        gs.PlayerNames = append(gs.PlayerNames[:seat], gs.PlayerNames[seat+1:]...)
+        gs.ClientSeeds = append(gs.ClientSeeds[:seat], gs.ClientSeeds[seat+1:]...)
+        if seat < len(gs.Seeded) {
+            gs.Seeded = append(gs.Seeded[:seat], gs.Seeded[seat+1:]...)
+        }
+        gs.Hands = append(gs.Hands[:seat], gs.Hands[seat+1:]...)
+        if seat < len(gs.Out) {
+            gs.Out = append(gs.Out[:seat], gs.Out[seat+1:]...)
+        }
+        if seat < len(gs.Banks) {
+            gs.Banks = append(gs.Banks[:seat], gs.Banks[seat+1:]...)
+        }
+        if seat < len(gs.Stacks) {
+            gs.Stacks = append(gs.Stacks[:seat], gs.Stacks[seat+1:]...)
+        }
+        if gs.Turn >= len(gs.Players) {
+            gs.Turn = 0
+        }
+        if gs.Button >= len(gs.Players) {
+            gs.Button = 0
+        }
+        return
+    }
+    gs.Players[seat] = ""
+    gs.ClientSeeds[seat] = ""
+    if seat < len(gs.Seeded) {
+        gs.Seeded[seat] = false
+    }
+    if seat < len(gs.Out) {
+        gs.Out[seat] = false
+    }
+    if !gs.Started() {
+        gs.PlayerNames[seat] = ""
+        gs.Hands[seat] = nil
+    }
+}
+
+// StandUp gives up the player's seat between hands.  They can still watch,
+// as a spectator.
+func (gs *GameState) StandUp(id string) error {
+    seat := gs.seatOf(id)
+    if seat < 0 {
+        return ErrNotInGame
+    }
+    if !gs.betweenHands() {
+        return ErrHandInProgress
+    }
+    gs.freeSeat(seat)
+    if has(gs.Watchers, id) {
+        gs.Watch(id)
+    }
+    return nil
+}
+
+// Leave stands the player up and stops sending them the game.
+func (gs *GameState) Leave(id string) error {
+    if err := gs.StandUp(id); err != nil {
+        return err
+    }
+    gs.Unwatch(id)
+    return nil
+}
+
+// SitOut keeps the player's seat but deals them out of hands until they sit
+// back in.  A player who sits out in the middle of a hand plays it out.
+func (gs *GameState) SitOut(id string, out bool) error {
+    seat := gs.seatOf(id)
+    if seat < 0 {
+        return ErrNotInGame
+    }
+    for len(gs.Out) < len(gs.Players) {
+        gs.Out = append(gs.Out, false)
+    }
+    gs.Out[seat] = out
+    if !gs.Started() {
+        if out {
+            gs.Hands[seat] = nil
+        } else if gs.Hands[seat] == nil {
+            gs.Hands[seat] = &ChineseHand{Low: gs.Spec.Low}
+        }
+    }
+    return nil
+}
+
+// deal gives a fresh hand to every seated player and none to the rest.
+func (gs *GameState) deal() {
+    for i := range gs.Players {
+        if !gs.seated(i) {
+            gs.Hands[i] = nil
+            if gs.Players[i] == "" {
+                gs.PlayerNames[i] = ""
+            }
+        } else if gs.Hands[i] == nil || gs.Hands[i].Count() > 0 {
+            gs.Hands[i] = &ChineseHand{Low: gs.Spec.Low}
+        }
+    }
+}
+
+// nextInHand is the first seat after seat that has a hand, or seat itself
+// if none other does.
+func (gs *GameState) nextInHand(seat int) int {
+    for k := 1; k <= len(gs.Hands); k++ {
+        if i := (seat + k) % len(gs.Hands); gs.Hands[i] != nil {
+            return i
+        }
+    }
+    return seat
+}
+
+// inHand counts the hands being played.
+func (gs *GameState) inHand() int {
+    n := 0
+    for _, hand := range gs.Hands {
+        if hand != nil {
+            n++
+        }
+    }
+    return n
+}
@@ -0,0 +1,112 @@
+package poker
+
+import (
+    "fmt"
+    "testing"
+)
+
+func TestSeats(t *testing.T) {
+    gs, err := NewGame(0, StandardDeck, SeededSource(5))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if gs.Match, err = NewMatch(FixedHands, 5); err != nil {
+        t.Fatal(err)
+    }
+    for i := 0; i < 3; i++ {
+        if err := gs.Sit(fmt.Sprint("p", i), fmt.Sprint("P", i), "", ""); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if err := gs.Start(); err != nil {
+        t.Fatal(err)
+    }
+    if err := gs.StandUp("p1"); err == nil {
+        t.Errorf("stood up in the middle of a hand")
+    }
+    playOut(t, gs)
+    if err := gs.StandUp("p1"); err != nil {
+        t.Fatal(err)
+    }
+    if gs.InGame("p1") || len(gs.Players) != 3 || gs.Hands[1] == nil {
+        t.Errorf("after standing up: %v, hand %v", gs.Players, gs.Hands[1])
+    }
+    if err := gs.SitOut("p2", true); err != nil {
+        t.Fatal(err)
+    }
+    if err := gs.NewHand(SeededSource(6)); err != nil {
+        t.Fatal(err)
+    }
+    if gs.Hands[1] != nil || gs.Hands[2] != nil || gs.PlayerNames[1] != "" {
+        t.Errorf("dealt in %v", gs.PlayerNames)
+    }
+    // The button skips the empty seat and the player sitting out.
+    if gs.Button != 0 {
+        t.Errorf("button at %d", gs.Button)
+    }
+    if err := gs.Sit("p3", "P3", "", ""); err != nil {
+        t.Fatal(err)
+    }
+    if gs.Players[1] != "p3" || gs.Hands[1] == nil {
+        t.Errorf("new player did not take the empty seat: %v", gs.Players)
+    }
+    if gs.ClientState("p3").Scores[1] != 0 || gs.Match.Scores["p1"] != gs.Match.Results[0].Points[1] {
+        t.Errorf("seat 1's score passed from p1 to p3: %v", gs.Match.Scores)
+    }
+    gs.Seed("p0", "")
+    if err := gs.Start(); err != nil {
+        t.Fatal(err)
+    }
+    for !gs.Finished() {
+        if gs.Turn == 2 {
+            t.Fatalf("turn came to a player sitting out")
+        }
+        for pos := Back; pos <= Front; pos++ {
+            if gs.Play(gs.Players[gs.Turn], 0, pos) == nil {
+                break
+            }
+        }
+    }
+    s := gs.Match.Scores
+    if s["p0"] + s["p1"] + s["p2"] + s["p3"] != 0 || gs.Match.Results[1].Points[2] != 0 {
+        t.Errorf("scores %v, last hand %v", s, gs.Match.Results[1].Points)
+    }
+    if err := gs.Leave("p0"); err != nil {
+        t.Fatal(err)
+    }
+    if gs.InGame("p0") || gs.Match.Over {
+        t.Errorf("after leaving a fixed match: %v, over %v", gs.Players, gs.Match.Over)
+    }
+}
+
+func TestLeaveOpenMatch(t *testing.T) {
+    departures := map[string]func(gs *GameState) error{
+        "leave": func(gs *GameState) error { return gs.Leave("b") },
+        "stand up": func(gs *GameState) error { return gs.StandUp("b") },
+        "kick": func(gs *GameState) error { return gs.Kick("a", gs.seatOf("b")) },
+    }
+    for name, depart := range departures {
+        gs, err := NewGame(0, StandardDeck, SeededSource(7))
+        if err != nil {
+            t.Fatal(err)
+        }
+        if gs.Match, err = NewMatch(UntilLeave, 0); err != nil {
+            t.Fatal(err)
+        }
+        gs.Owner = "a"
+        gs.Sit("a", "A", "", "")
+        gs.Sit("b", "B", "", "")
+        if err := depart(gs); err != nil || len(gs.Players) != 1 || gs.Match.Over {
+            t.Errorf("%s before the first hand: %v %v", name, err, gs.Players)
+        }
+        gs.Sit("b", "B", "", "")
+        gs.Start()
+        playOut(t, gs)
+        if err := depart(gs); err != nil {
+            t.Fatalf("%s: %v", name, err)
+        }
+        if !gs.Match.Over {
+            t.Errorf("open match went on after a player's %s", name)
+        }
+    }
+}
@@ -0,0 +1,111 @@
+package poker
+
+// ScoopBonus is what winning all three rows against a player is worth on top
+// of the three rows themselves.
+const ScoopBonus = 3
+
+// RowResult is how two players' hands compare in one row.
+type RowResult struct {
+    Pos int
+    Hands [2]*Hand
+    Descriptions [2]string
+    Royalties [2]int
+    Result int           // 1 if the first player wins the row, -1 if the second does, 0 if tied
+    Explanation string
+}
+
+// Matchup settles one pair of players row by row: a point a row, the scoop
+// bonus, and the difference in royalties.  A fouled hand loses every row to a
+// hand that did not foul and earns no royalties.
+type Matchup struct {
+    Players [2]int
+    Fouled [2]bool
+    Rows []*RowResult
+    Scooped [2]bool      // Scooped[i] if player i won all three rows
+    Points int           // net points to the first player, royalties included
+}
+
+// Showdown is the result of a finished hand: every pair of players, and each
+// player's net points against the whole table.
+type Showdown struct {
+    Matchups []*Matchup
+    Totals []int
+}
+
+func newMatchup(hands []*ChineseHand, i, j int) *Matchup {
+    m := &Matchup{Players: [2]int{i, j}, Fouled: [2]bool{hands[i].Fault(), hands[j].Fault()}}
+    won := [2]int{}
+    for pos := Back; pos <= Front; pos++ {
+        a, b := hands[i].Row(pos), hands[j].Row(pos)
+        row := &RowResult{
+            Pos: pos,
+            Hands: [2]*Hand{a, b},
+            Descriptions: [2]string{a.Describe(), b.Describe()},
+        }
+        switch {
+            case m.Fouled[0] && m.Fouled[1]:
+                row.Explanation = "both fouled"
+            case m.Fouled[0]:
+                row.Result = -1
+                row.Explanation = "first hand fouled"
+            case m.Fouled[1]:
+                row.Result = 1
+                row.Explanation = "second hand fouled"
+            default:
+                row.Result = outcome(a.Compare(b))
+                row.Explanation = CompareExplain(a, b)
+        }
+        if !m.Fouled[0] {
+            row.Royalties[0] = rowRoyalty(pos, a)
+        }
+        if !m.Fouled[1] {
+            row.Royalties[1] = rowRoyalty(pos, b)
+        }
+        if row.Result > 0 {
+            won[0]++
+        } else if row.Result < 0 {
+            won[1]++
+        }
+        m.Points += row.Result + row.Royalties[0] - row.Royalties[1]
+        m.Rows = append(m.Rows, row)
+    }
+    for k := 0; k < 2; k++ {
+        m.Scooped[k] = won[k] == len(m.Rows)
+    }
+    if m.Scooped[0] {
+        m.Points += ScoopBonus
+    } else if m.Scooped[1] {
+        m.Points -= ScoopBonus
+    }
+    return m
+}
+
+// outcome is 1, 0 or -1 as the result of a Compare is a win, a tie or a
+// loss.
+func outcome(x int) int {
+    switch {
+        case x < 0:
+            return -1
+        case x > 0:
+            return 1
+    }
+    return 0
+}
+
+// NewShowdown settles finished hands between every pair of players.  Seats
+// with no hand take no part.
+func NewShowdown(hands []*ChineseHand) *Showdown {
+    s := &Showdown{Totals: make([]int, len(hands))}
+    for i := range hands {
+        for j := i + 1; j < len(hands); j++ {
+            if hands[i] == nil || hands[j] == nil {
+                continue
+            }
+            m := newMatchup(hands, i, j)
+            s.Matchups = append(s.Matchups, m)
+            s.Totals[i] += m.Points
+            s.Totals[j] -= m.Points
+        }
+    }
+    return s
+}
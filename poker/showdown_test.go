@@ -0,0 +1,42 @@
+package poker
+
+import (
+    "testing"
+)
+
+func chineseHand(t *testing.T, back, middle, front string) *ChineseHand {
+    return &ChineseHand{Back: handOf(t, back), Middle: handOf(t, middle), Front: handOf(t, front)}
+}
+
+func TestShowdown(t *testing.T) {
+    hands := []*ChineseHand{
+        // Trips, two pair and a pair of Queens: 7 points of royalties.
+        chineseHand(t, "7s7h7d2c3s", "AsAh5d5c4s", "QsQh2d"),
+        chineseHand(t, "6s6h6d9cTs", "KsKhJdJc4h", "8s9hTd"),
+        // Fouled: the front beats the middle.
+        chineseHand(t, "AdAcKdKc2h", "3s4h6d8cTh", "JsJhJc"),
+    }
+    s := NewShowdown(hands)
+    if len(s.Matchups) != 3 {
+        t.Fatalf("got %d matchups", len(s.Matchups))
+    }
+    m := s.Matchups[0]
+    if !m.Scooped[0] || m.Scooped[1] || m.Points != 3 + ScoopBonus + 7 {
+        t.Errorf("first matchup: scooped %v, points %d", m.Scooped, m.Points)
+    }
+    m = s.Matchups[1]
+    if !m.Fouled[1] || !m.Scooped[0] || m.Rows[2].Royalties[1] != 0 || m.Points != 3 + ScoopBonus + 7 {
+        t.Errorf("second matchup: fouled %v, scooped %v, points %d", m.Fouled, m.Scooped, m.Points)
+    }
+    m = s.Matchups[2]
+    if m.Points != 3 + ScoopBonus {
+        t.Errorf("third matchup: points %d", m.Points)
+    }
+    total := 0
+    for _, p := range s.Totals {
+        total += p
+    }
+    if total != 0 || s.Totals[0] != 26 {
+        t.Errorf("totals %v", s.Totals)
+    }
+}
@@ -0,0 +1,235 @@
+package poker
+
+import (
+    "errors"
+    "time"
+)
+
+// What spectators are shown of the hands.
+const (
+    SpectatePlaced = iota  // cards once they are placed, never the cards being dealt
+    SpectateAfterHand      // nothing until the hand is over
+    SpectateNone           // no hands at all
+)
+
+const MaxSpectatorDelay = time.Hour
+
+// Move is one card placed, as logged for the hand in progress.
+type Move struct {
+    Seat int
+    Card Card
+    Pos int
+    Dealt int  // DeckPos when it was placed, which is the same all turn
+    Time time.Time
+}
+
+// SetSpectators sets what spectators see, and how far behind the play they
+// see it.
+func (gs *GameState) SetSpectators(view int, delay time.Duration) error {
+    if view < SpectatePlaced || view > SpectateNone {
+        return errors.New("Unknown spectator view")
+    }
+    if delay < 0 || delay > MaxSpectatorDelay {
+        return errors.New("Spectator delay is out of range")
+    }
+    gs.SpectatorView, gs.SpectatorDelay = view, delay
+    return nil
+}
+
+// Watch adds id to those sent the game: to Watchers if they are seated, to
+// Spectators if not.  It reports whether anything changed.
+func (gs *GameState) Watch(id string) bool {
+    if gs.InGame(id) {
+        gs.Spectators = without(gs.Spectators, id)
+        if has(gs.Watchers, id) {
+            return false
+        }
+        gs.Watchers = append(gs.Watchers, id)
+        return true
+    }
+    if has(gs.Spectators, id) {
+        return false
+    }
+    gs.Watchers = without(gs.Watchers, id)
+    gs.Spectators = append(gs.Spectators, id)
+    return true
+}
+
+// Unwatch stops sending id the game.
+func (gs *GameState) Unwatch(id string) {
+    gs.Watchers = without(gs.Watchers, id)
+    gs.Spectators = without(gs.Spectators, id)
+}
+
+// Audience is everyone the game is sent to.
+func (gs *GameState) Audience() []string {
+    return append(append([]string{}, gs.Watchers...), gs.Spectators...)
+}
+
+func has(ids []string, id string) bool {
+    for _, x := range ids {
+        if x == id {
+            return true
+        }
+    }
+    return false
+}
+
+func without(ids []string, id string) []string {
+    result := make([]string, 0, len(ids))
+    for _, x := range ids {
+        if x != id {
+            result = append(result, x)
+        }
+    }
+    return result
+}
+
+// Scores are a table's score fields, kept as they stood before a hand was
+// scored for spectators who have yet to see it end.
+type Scores struct {
+    Match *Match
+    Stacks []int
+    HandsPlayed int
+}
+
+func (gs *GameState) scores() *Scores {
+    sc := &Scores{Stacks: append([]int{}, gs.Stacks...), HandsPlayed: gs.HandsPlayed}
+    if gs.Match != nil {
+        m := *gs.Match
+        m.Scores = make(map[string]int)
+        for id, score := range gs.Match.Scores {
+            m.Scores[id] = score
+        }
+        m.Results = m.Results[:len(m.Results):len(m.Results)]
+        sc.Match = &m
+    }
+    return sc
+}
+
+func (v *GameState) setScores(sc *Scores) {
+    v.Match, v.Stacks, v.HandsPlayed = sc.Match, sc.Stacks, sc.HandsPlayed
+}
+
+// PastHand is the hand before the one being played, kept for spectators
+// who are still watching it.
+type PastHand struct {
+    Hands []*ChineseHand
+    PlayerNames []string
+    Moves []Move
+    Before *Scores  // the scores before it ended
+    Ended time.Time  // when the next hand was shuffled
+}
+
+// keepHand keeps the hand just finished, as NewHand is about to clear it,
+// if spectators are behind the play.
+func (gs *GameState) keepHand() {
+    gs.LastHand = nil
+    if gs.SpectatorDelay == 0 || len(gs.Moves) == 0 {
+        return
+    }
+    gs.LastHand = &PastHand{
+        Hands: append([]*ChineseHand{}, gs.Hands...),
+        PlayerNames: append([]string{}, gs.PlayerNames...),
+        Moves: gs.Moves,
+        Before: gs.ScoresBefore,
+        Ended: gs.now(),
+    }
+}
+
+// replayed is the hands as moves up to cutoff left them, and how many of
+// the moves that was.
+func replayed(hands []*ChineseHand, moves []Move, low Rank, cutoff time.Time) ([]*ChineseHand, int) {
+    result := make([]*ChineseHand, len(hands))
+    for i, hand := range hands {
+        if hand != nil {
+            result[i] = &ChineseHand{Low: low}
+        }
+    }
+    for i, m := range moves {
+        if m.Time.After(cutoff) {
+            return result, i
+        }
+        if m.Seat < len(result) && result[m.Seat] != nil {
+            result[m.Seat].Place(m.Pos, moves[i].Card)
+        }
+    }
+    return result, len(moves)
+}
+
+// spectated is the game as spectators may see it: the hands as they stood
+// SpectatorDelay ago, and no cards that have not been placed.  Until they
+// have seen a hand end, the scores are those from before it, and until
+// they have seen the next hand shuffled, they are shown the last one.
+func (gs *GameState) spectated() *GameState {
+    v := *gs
+    v.Showing = nil
+    v.Stacks = append([]int{}, gs.Stacks...)
+    cutoff := gs.now().Add(-gs.SpectatorDelay)
+    // The scores once the last hand is over, and once this one is.
+    afterLast, after := gs.scores(), gs.scores()
+    if gs.ScoresBefore != nil {
+        afterLast = gs.ScoresBefore
+    }
+    if last := gs.LastHand; last != nil && cutoff.Before(last.Ended) {
+        hands, shown := replayed(last.Hands, last.Moves, gs.Spec.Low, cutoff)
+        v.Hands, v.PlayerNames = hands, last.PlayerNames
+        // The fairness of the last hand is not kept, and this one's is
+        // not to be given away.
+        v.ServerSeed, v.Deck, v.DeckPos = "", nil, len(last.Moves)
+        // The turn is whoever played next, so that the hand is finished
+        // once its last card is shown, and not before.
+        if shown < len(last.Moves) {
+            v.Turn = last.Moves[shown].Seat
+            v.setScores(afterLast)
+            if last.Before != nil {
+                v.setScores(last.Before)
+            }
+        } else {
+            v.Turn = last.Moves[shown - 1].Seat
+            v.setScores(afterLast)
+        }
+    } else {
+        hands, shown := replayed(gs.Hands, gs.Moves, gs.Spec.Low, cutoff)
+        v.Hands = hands
+        if shown < len(gs.Moves) {
+            v.Turn = gs.Moves[shown].Seat
+            v.setScores(afterLast)
+        } else if gs.Started() {
+            v.setScores(after)
+        } else {
+            v.setScores(afterLast)
+        }
+    }
+    if gs.SpectatorView == SpectateNone || (gs.SpectatorView == SpectateAfterHand && !v.Finished()) {
+        for i, hand := range v.Hands {
+            if hand != nil {
+                v.Hands[i] = &ChineseHand{Low: gs.Spec.Low}
+            }
+        }
+    }
+    return &v
+}
+
+// Caught is when spectators will have seen the last move, and the last hand
+// give way to this one, or the zero time if they already have.
+func (gs *GameState) Caught() time.Time {
+    if gs.SpectatorDelay == 0 {
+        return time.Time{}
+    }
+    var last time.Time
+    if gs.LastHand != nil {
+        last = gs.LastHand.Ended
+    }
+    if len(gs.Moves) > 0 && gs.Moves[len(gs.Moves) - 1].Time.After(last) {
+        last = gs.Moves[len(gs.Moves) - 1].Time
+    }
+    if last.IsZero() {
+        return time.Time{}
+    }
+    at := last.Add(gs.SpectatorDelay)
+    if !at.After(gs.now()) {
+        return time.Time{}
+    }
+    return at
+}
@@ -0,0 +1,151 @@
+package poker
+
+import (
+    "testing"
+    "time"
+)
+
+func spectatedGame(t *testing.T, view int, delay time.Duration) (*GameState, *time.Time) {
+    gs, err := NewGame(0, StandardDeck, SeededSource(10))
+    if err != nil {
+        t.Fatal(err)
+    }
+    now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+    gs.clock = func() time.Time { return now }
+    if err := gs.SetSpectators(view, delay); err != nil {
+        t.Fatal(err)
+    }
+    gs.Sit("a", "A", "", "")
+    gs.Sit("b", "B", "", "")
+    gs.Watch("a")
+    gs.Watch("s")
+    if err := gs.Start(); err != nil {
+        t.Fatal(err)
+    }
+    return gs, &now
+}
+
+func placed(cgs *ClientGameState) int {
+    n := 0
+    for _, hand := range cgs.Hands {
+        if hand != nil {
+            n += hand.Count()
+        }
+    }
+    return n
+}
+
+func TestSpectatorRoles(t *testing.T) {
+    gs, _ := spectatedGame(t, SpectatePlaced, 0)
+    if len(gs.Watchers) != 1 || len(gs.Spectators) != 1 || len(gs.Audience()) != 2 {
+        t.Errorf("watchers %v, spectators %v", gs.Watchers, gs.Spectators)
+    }
+    if cgs := gs.ClientState("a"); cgs.Spectating || cgs.Spectators != 1 || len(cgs.Showing) != 5 {
+        t.Errorf("player's view %+v", cgs)
+    }
+    cgs := gs.ClientState("s")
+    if !cgs.Spectating || len(cgs.Showing) != 0 || cgs.Odds != nil {
+        t.Errorf("spectator was shown %v", cgs.Showing)
+    }
+}
+
+func TestSpectatorDelay(t *testing.T) {
+    gs, now := spectatedGame(t, SpectatePlaced, time.Minute)
+    gs.Play("a", 0, Back)
+    *now = now.Add(30 * time.Second)
+    gs.Play("a", 0, Back)
+    if n := placed(gs.ClientState("s")); n != 0 {
+        t.Errorf("spectator saw %d cards before the delay", n)
+    }
+    if gs.Caught().IsZero() {
+        t.Errorf("no catch-up after a move")
+    }
+    *now = now.Add(30 * time.Second)
+    if n := placed(gs.ClientState("s")); n != 1 {
+        t.Errorf("spectator saw %d cards, want 1", n)
+    }
+    *now = now.Add(time.Hour)
+    if n := placed(gs.ClientState("s")); n != 2 || !gs.Caught().IsZero() {
+        t.Errorf("spectator saw %d cards, want 2", n)
+    }
+}
+
+func TestSpectatorViews(t *testing.T) {
+    for _, view := range []int{SpectateAfterHand, SpectateNone} {
+        gs, _ := spectatedGame(t, view, 0)
+        gs.Play("a", 0, Back)
+        if n := placed(gs.ClientState("s")); n != 0 {
+            t.Errorf("view %d: spectator saw %d cards mid-hand", view, n)
+        }
+        playOut(t, gs)
+        cgs := gs.ClientState("s")
+        if finished := cgs.Finished && cgs.Showdown != nil; finished != (view == SpectateAfterHand) {
+            t.Errorf("view %d: spectator saw the showdown: %v", view, finished)
+        }
+    }
+    gs, _ := spectatedGame(t, SpectatePlaced, 0)
+    if err := gs.SetSpectators(SpectateNone + 1, 0); err == nil {
+        t.Errorf("accepted an unknown view")
+    }
+}
+
+func TestSpectatorLastHand(t *testing.T) {
+    gs, now := spectatedGame(t, SpectatePlaced, time.Minute)
+    var err error
+    if gs.Match, err = NewMatch(FixedHands, 3); err != nil {
+        t.Fatal(err)
+    }
+    playOut(t, gs)
+    cgs := gs.ClientState("s")
+    if len(cgs.Match.Results) != 0 || cgs.Finished {
+        t.Errorf("spectator saw the hand scored before it ended")
+    }
+    *now = now.Add(30 * time.Second)
+    if err := gs.NewHand(SeededSource(11)); err != nil {
+        t.Fatal(err)
+    }
+    gs.Seed("a", "")
+    gs.Seed("b", "")
+    if err := gs.Start(); err != nil {
+        t.Fatal(err)
+    }
+    gs.Play(gs.Players[gs.Turn], 0, Back)
+    *now = now.Add(45 * time.Second)
+    cgs = gs.ClientState("s")
+    if !cgs.Finished || placed(cgs) != 26 || len(cgs.Match.Results) != 1 || cgs.Fairness != nil {
+        t.Errorf("spectator did not see the last hand end: %d cards, %d results", placed(cgs), len(cgs.Match.Results))
+    }
+    if gs.Caught().IsZero() {
+        t.Errorf("no catch-up while the last hand is shown")
+    }
+    *now = now.Add(time.Hour)
+    if cgs = gs.ClientState("s"); cgs.Finished || placed(cgs) != 1 || !gs.Caught().IsZero() {
+        t.Errorf("spectator saw %d cards of the new hand, want 1", placed(cgs))
+    }
+}
+
+func TestSpectatorLastCard(t *testing.T) {
+    for _, view := range []int{SpectatePlaced, SpectateAfterHand} {
+        gs, now := spectatedGame(t, view, time.Minute)
+        for len(gs.Moves) < 25 {
+            played := false
+            for pos := Back; pos <= Front && !played; pos++ {
+                played = gs.Play(gs.Players[gs.Turn], 0, pos) == nil
+            }
+        }
+        *now = now.Add(time.Hour)
+        playOut(t, gs)
+        *now = now.Add(30 * time.Second)
+        cgs := gs.ClientState("s")
+        if cgs.Finished || cgs.Showdown != nil {
+            t.Errorf("view %d: spectator saw the hand finished before its last card", view)
+        }
+        if want := map[int]int{SpectatePlaced: 25, SpectateAfterHand: 0}[view]; placed(cgs) != want {
+            t.Errorf("view %d: spectator saw %d cards, want %d", view, placed(cgs), want)
+        }
+        *now = now.Add(time.Minute)
+        if cgs = gs.ClientState("s"); !cgs.Finished || cgs.Showdown == nil || placed(cgs) != 26 {
+            t.Errorf("view %d: spectator did not see the hand end", view)
+        }
+    }
+}
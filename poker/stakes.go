@@ -0,0 +1,217 @@
+package poker
+
+import (
+    "encoding/csv"
+    "errors"
+    "fmt"
+    "io"
+    "sort"
+    "strconv"
+    "time"
+)
+
+// Stakes are what a table plays for: each point is worth PointValue, and
+// players sit down with BuyIn points, and are dealt out once they have lost
+// them.  With no buy-in there is no limit.
+type Stakes struct {
+    PointValue float64
+    BuyIn int
+}
+
+func NewStakes(value float64, buyIn int) (*Stakes, error) {
+    if value <= 0 {
+        return nil, errors.New("Point value must be positive")
+    }
+    if buyIn < 0 {
+        return nil, errors.New("Buy-in cannot be negative")
+    }
+    return &Stakes{value, buyIn}, nil
+}
+
+// Stack is the points the seat has left of its buy-in.
+func (gs *GameState) Stack(seat int) int {
+    for len(gs.Stacks) <= seat {
+        gs.Stacks = append(gs.Stacks, gs.Stakes.BuyIn)
+    }
+    return gs.Stacks[seat]
+}
+
+func (gs *GameState) buyIns() bool {
+    return gs.Stakes != nil && gs.Stakes.BuyIn > 0
+}
+
+// busted is whether the seat has lost its whole buy-in.
+func (gs *GameState) busted(seat int) bool {
+    return gs.buyIns() && gs.Stack(seat) <= 0
+}
+
+// payStacks moves the finished hand's transfers between the seats' stacks.
+func (gs *GameState) payStacks() {
+    if !gs.buyIns() {
+        return
+    }
+    for _, t := range NewShowdown(gs.Hands).Transfers() {
+        gs.Stacks[t.From] = gs.Stack(t.From) - t.Points
+        gs.Stacks[t.To] = gs.Stack(t.To) + t.Points
+    }
+}
+
+// Payouts returns the points each player collects from each other player:
+// Payouts()[i][j] is what i takes from j, and is negative if i pays j.
+func (s *Showdown) Payouts() [][]int {
+    p := make([][]int, len(s.Totals))
+    for i := range p {
+        p[i] = make([]int, len(s.Totals))
+    }
+    for _, m := range s.Matchups {
+        i, j := m.Players[0], m.Players[1]
+        p[i][j] += m.Points
+        p[j][i] -= m.Points
+    }
+    return p
+}
+
+// Transfer moves points from one seat to another.
+type Transfer struct {
+    From, To int
+    Points int
+}
+
+// Transfers lists who pays whom for the hand, from the payout matrix.
+func (s *Showdown) Transfers() []Transfer {
+    transfers := make([]Transfer, 0)
+    p := s.Payouts()
+    for i := range p {
+        for j := range p[i] {
+            if p[i][j] > 0 {
+                transfers = append(transfers, Transfer{From: j, To: i, Points: p[i][j]})
+            }
+        }
+    }
+    return transfers
+}
+
+// LedgerEntry is one transfer between two players, as stored.
+type LedgerEntry struct {
+    Game string
+    Hand int
+    From, To string
+    FromName, ToName string
+    Points int
+    PointValue float64
+    Time time.Time
+}
+
+// Settlement is one payment that squares a session, between player ids.
+type Settlement struct {
+    From, To string
+    Points int
+    Amount float64
+}
+
+func (s Settlement) String() string {
+    return fmt.Sprintf("%s owes %s %d points", s.From, s.To, s.Points)
+}
+
+// Named is the settlement with the players' names from names in place of
+// their ids.
+func (s Settlement) Named(names map[string]string) string {
+    return fmt.Sprintf("%s owes %s %d points", names[s.From], names[s.To], s.Points)
+}
+
+// Net sums the ledger into each player's net points, keyed by player id.
+func Net(entries []*LedgerEntry) map[string]int {
+    net := make(map[string]int)
+    for _, e := range entries {
+        net[e.From] -= e.Points
+        net[e.To] += e.Points
+    }
+    return net
+}
+
+// Names maps each player id in the ledger to the name they played under,
+// numbered where two players went by the same one.
+func Names(entries []*LedgerEntry) map[string]string {
+    names := make(map[string]string)
+    taken := make(map[string]bool)
+    add := func(id, name string) {
+        if _, ok := names[id]; ok {
+            return
+        }
+        unique := name
+        for n := 2; taken[unique]; n++ {
+            unique = fmt.Sprintf("%s (%d)", name, n)
+        }
+        names[id] = unique
+        taken[unique] = true
+    }
+    for _, e := range entries {
+        add(e.From, e.FromName)
+        add(e.To, e.ToName)
+    }
+    return names
+}
+
+// Settle turns net results into payments, the biggest loser paying the
+// biggest winner first, so that nobody pays more than once per winner.
+func Settle(net map[string]int, pointValue float64) []Settlement {
+    type balance struct {
+        name string
+        points int
+    }
+    owes, owed := make([]*balance, 0), make([]*balance, 0)
+    for name, points := range net {
+        if points < 0 {
+            owes = append(owes, &balance{name, -points})
+        } else if points > 0 {
+            owed = append(owed, &balance{name, points})
+        }
+    }
+    byPoints := func(b []*balance) {
+        sort.Slice(b, func(i, j int) bool {
+            if b[i].points != b[j].points {
+                return b[i].points > b[j].points
+            }
+            return b[i].name < b[j].name
+        })
+    }
+    byPoints(owes)
+    byPoints(owed)
+    settlements := make([]Settlement, 0)
+    for i, j := 0, 0; i < len(owes) && j < len(owed); {
+        n := min(owes[i].points, owed[j].points)
+        settlements = append(settlements, Settlement{owes[i].name, owed[j].name, n, float64(n) * pointValue})
+        owes[i].points -= n
+        owed[j].points -= n
+        if owes[i].points == 0 {
+            i++
+        }
+        if owed[j].points == 0 {
+            j++
+        }
+    }
+    return settlements
+}
+
+// WriteLedgerCSV writes every transfer and then the settlement, naming the
+// players from names.
+func WriteLedgerCSV(w io.Writer, entries []*LedgerEntry, settlements []Settlement, names map[string]string) error {
+    out := csv.NewWriter(w)
+    out.Write([]string{"hand", "time", "from", "to", "points", "amount"})
+    for _, e := range entries {
+        out.Write([]string{
+            strconv.Itoa(e.Hand),
+            e.Time.Format(time.RFC3339),
+            names[e.From],
+            names[e.To],
+            strconv.Itoa(e.Points),
+            strconv.FormatFloat(float64(e.Points) * e.PointValue, 'f', 2, 64),
+        })
+    }
+    for _, s := range settlements {
+        out.Write([]string{"settlement", "", names[s.From], names[s.To],
+            strconv.Itoa(s.Points), strconv.FormatFloat(s.Amount, 'f', 2, 64)})
+    }
+    out.Flush()
+    return out.Error()
+}
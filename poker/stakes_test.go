@@ -0,0 +1,114 @@
+package poker
+
+import (
+    "bytes"
+    "strings"
+    "testing"
+)
+
+func TestTransfers(t *testing.T) {
+    hands := []*ChineseHand{
+        chineseHand(t, "7s7h7d2c3s", "AsAh5d5c4s", "QsQh2d"),
+        chineseHand(t, "6s6h6d9cTs", "KsKhJdJc4h", "8s9hTd"),
+        chineseHand(t, "AdAcKdKc2h", "3s4h6d8cTh", "JsJhJc"),
+    }
+    s := NewShowdown(hands)
+    p := s.Payouts()
+    for i := range p {
+        for j := range p {
+            if p[i][j] != -p[j][i] {
+                t.Errorf("payouts %v are not antisymmetric", p)
+            }
+        }
+    }
+    received := make([]int, len(hands))
+    for _, tr := range s.Transfers() {
+        if tr.Points <= 0 {
+            t.Errorf("transfer %v", tr)
+        }
+        received[tr.To] += tr.Points
+        received[tr.From] -= tr.Points
+    }
+    for i, total := range s.Totals {
+        if received[i] != total {
+            t.Errorf("player %d received %d but won %d", i, received[i], total)
+        }
+    }
+}
+
+func TestSettle(t *testing.T) {
+    // a and c both go by A.
+    entries := []*LedgerEntry{
+        {From: "a", To: "b", FromName: "A", ToName: "B", Points: 20},
+        {From: "c", To: "b", FromName: "A", ToName: "B", Points: 14},
+        {From: "c", To: "a", FromName: "A", ToName: "A", Points: 6},
+    }
+    net := Net(entries)
+    if net["a"] != -14 || net["c"] != -20 || net["b"] != 34 {
+        t.Errorf("net %v", net)
+    }
+    names := Names(entries)
+    if names["a"] != "A" || names["c"] != "A (2)" {
+        t.Errorf("names %v", names)
+    }
+    settlements := Settle(net, 0.5)
+    got := make([]string, 0)
+    for _, s := range settlements {
+        got = append(got, s.Named(names))
+    }
+    want := "A (2) owes B 20 points; A owes B 14 points"
+    if strings.Join(got, "; ") != want {
+        t.Errorf("got %q, want %q", strings.Join(got, "; "), want)
+    }
+    if settlements[0].Amount != 10 {
+        t.Errorf("amount %v", settlements[0].Amount)
+    }
+    var buf bytes.Buffer
+    if err := WriteLedgerCSV(&buf, entries, settlements, names); err != nil {
+        t.Fatal(err)
+    }
+    if lines := strings.Count(buf.String(), "\n"); lines != 1 + len(entries) + len(settlements) ||
+        !strings.Contains(buf.String(), "settlement,,A (2),B,20") {
+        t.Errorf("CSV has %d lines:\n%s", lines, buf.String())
+    }
+}
+
+func TestStacks(t *testing.T) {
+    gs, err := NewGame(0, StandardDeck, SeededSource(8))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if gs.Stakes, err = NewStakes(1, 5); err != nil {
+        t.Fatal(err)
+    }
+    gs.Sit("a", "A", "", "")
+    gs.Sit("b", "B", "", "")
+    if err := gs.Start(); err != nil {
+        t.Fatal(err)
+    }
+    playOut(t, gs)
+    totals := NewShowdown(gs.Hands).Totals
+    loser := 0
+    for i, total := range totals {
+        if gs.Stack(i) != 5 + total {
+            t.Errorf("seat %d has %d after winning %d", i, gs.Stack(i), total)
+        }
+        if total < totals[loser] {
+            loser = i
+        }
+    }
+    if totals[loser] > -5 {
+        t.Fatalf("nobody lost their buy-in: %v", totals)
+    }
+    gs.NewHand(SeededSource(9))
+    if gs.Hands[loser] != nil {
+        t.Error("dealt in a player with no points left")
+    }
+    // Sitting down again buys in again.
+    id := gs.Players[loser]
+    gs.StandUp(id)
+    gs.Sit(id, "again", "", "")
+    if gs.Stack(loser) != 5 || gs.Hands[loser] == nil {
+        t.Errorf("bought back in with %d", gs.Stack(loser))
+    }
+}
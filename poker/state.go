@@ -6,9 +6,9 @@ import (
     "bytes"
     "encoding/gob"
     "encoding/json"
-    "errors"
     //"fmt"
     "net/http"
+    "time"
 )
 
 const (
@@ -26,10 +26,36 @@ type ClientGameState struct {
     Showing []Card
     Turn int
     Faults []bool
+    Odds *FoulOdds
+    Fairness *Fairness
     Royalties []int
     Payouts [][]float32
     BackWinners, MiddleWinners, FrontWinners []int
+    Descriptions [][]string  // each hand's back, middle and front, in words
+    Explanations []string    // why each row was won, once the hand is over
+    Showdown *Showdown       // every pair of players settled, once the hand is over
+    Match *Match
+    Scores []int      // each seat's player's match score, in a match
+    Stakes *Stakes
+    Stacks []int      // each seat's points left of the buy-in, at a table with one
+    Owner bool        // the viewer owns the table
+    InviteCode string // shown only to the players
+    Private, Locked bool
     Players []string
+    SittingOut []bool
+    Seat int  // the viewer's, or -1
+    Deadline time.Time     // when the player to act runs out of time, on a timed table
+    TurnTime time.Duration
+    Banks []time.Duration  // each seat's time bank left
+    Async bool
+    Spectating bool  // the viewer is not seated, and sees the spectators' view
+    Spectators int
+    Chat []ChatMessage
+    UndoPolicy int
+    Undo *UndoRequest
+    CanTakeBack bool
+    NeedSeed bool  // the viewer is to be dealt in and has yet to add a seed
+    MaxPlayers int
     MyTurn bool
     InGame bool
     Started bool
@@ -39,6 +65,7 @@ type ClientGameState struct {
 
 type GameState struct {
     Deck
+    Spec DeckSpec
     DeckPos int
     Hands []*ChineseHand
     Button int
@@ -47,52 +74,236 @@ type GameState struct {
     Watchers []string
     Players []string
     PlayerNames []string
+    ClientSeeds []string
+    Seeded []bool  // by seat, whether the player has added a seed for this hand
+    ServerSeed string  // secret until the hand is over
+    Match *Match
+    Stakes *Stakes
+    Stacks []int  // by seat, points left of the buy-in
+    HandsPlayed int
+    Owner string
+    Private bool
+    InviteCode string
+    Reservations []string
+    Locked bool
+    Out []bool  // by seat, players sitting out
+    TurnTime, TimeBank time.Duration
+    Banks []time.Duration  // by seat, time bank left
+    TurnStart, Deadline time.Time
+    Async bool  // a correspondence game
+    Spectators []string  // those watching who are not seated
+    SpectatorView int
+    SpectatorDelay time.Duration
+    Moves []Move  // the cards placed this hand, in order
+    ScoresBefore *Scores  // the scores before this hand ended, for spectators
+    LastHand *PastHand  // the hand before, while spectators may be watching it
+    PlacedReveal int  // when placed cards are shown to the other players
+    Chat []storedChat
+    Mutes map[string][]string  // by player, the chat senders they have muted
+    ChatKey string  // secret key for chat senders
+    UndoPolicy int
+    Undo *UndoRequest  // waiting for the other players to approve
     key *datastore.Key
+    src RandSource
+    clock func() time.Time
+    shuffler Shuffler
 }
 
+// ClientState is the game as the player id sees it, with every card they may
+// not see hidden.  Anyone not seated sees the spectators' view.
 func (gs *GameState) ClientState(id string) *ClientGameState {
+    if seat := gs.seatOf(id); seat >= 0 {
+        cgs := gs.clientState(id)
+        gs.redact(cgs, seat)
+        return cgs
+    }
+    v := gs.spectated()
+    cgs := v.clientState(id)
+    v.redact(cgs, -1)
+    cgs.Spectating = true
+    return cgs
+}
+
+func (gs *GameState) clientState(id string) *ClientGameState {
     cgs := &ClientGameState{
         Hands:gs.Hands,
         GameId:gs.key.Encode(),
         Players:gs.PlayerNames,
+        MaxPlayers:gs.MaxPlayers(),
         Started:gs.Started(),
         Finished:gs.Finished(),
-        MyTurn:len(gs.Players) > 0 && gs.Players[gs.Turn] == id,
+        MyTurn:gs.Turn < len(gs.Players) && gs.Players[gs.Turn] == id,
         InGame:gs.InGame(id),
+        Seat:gs.seatOf(id),
+        Match:gs.Match,
+        Stakes:gs.Stakes,
+        Owner:gs.IsOwner(id),
+        Private:gs.Private,
+        Locked:gs.Locked,
+        Deadline:gs.Deadline,
+        TurnTime:gs.TurnTime,
+        Banks:gs.Banks,
+        Async:gs.Async,
+        Spectators:len(gs.Spectators),
+        Chat:gs.chatFor(id),
+        UndoPolicy:gs.UndoPolicy,
+        Undo:gs.Undo,
+        CanTakeBack:gs.CanTakeBack(id),
+        NeedSeed:gs.needsSeed(id),
     }
-    for _, hand := range gs.Hands {
+    if gs.InGame(id) || gs.IsOwner(id) {
+        cgs.InviteCode = gs.InviteCode
+    }
+    if gs.buyIns() {
+        for i := range gs.Players {
+            cgs.Stacks = append(cgs.Stacks, gs.Stack(i))
+        }
+    }
+    if gs.Match != nil {
+        for _, p := range gs.Players {
+            cgs.Scores = append(cgs.Scores, gs.Match.Scores[p])
+        }
+    }
+    for i, hand := range gs.Hands {
+        cgs.SittingOut = append(cgs.SittingOut, gs.SittingOut(i))
+        if hand == nil {
+            cgs.Royalties = append(cgs.Royalties, 0)
+            cgs.Descriptions = append(cgs.Descriptions, nil)
+            continue
+        }
         cgs.Royalties = append(cgs.Royalties, hand.Royalties())
+        cgs.Descriptions = append(cgs.Descriptions, []string{
+            hand.Back.Describe(), hand.Middle.Describe(), hand.Front.Describe()})
     }
     if len(gs.Showing) != 0 {
         cgs.Showing = gs.Showing
         cgs.Turn = gs.Turn
+        if cgs.MyTurn {
+            // The odds are only advice; leave them out if they can't be had.
+            if odds, err := gs.Hands[gs.Turn].Odds(gs.liveTo(gs.Turn), foulSamples, gs.source()); err == nil {
+                cgs.Odds = odds
+            }
+        }
     } else {
         cgs.Faults = Faults(gs.Hands)
     }
+    if gs.ServerSeed != "" {
+        cgs.Fairness = &Fairness{
+            Spec: gs.Spec,
+            Commitment: Commitment(gs.ServerSeed),
+            ClientSeeds: gs.ClientSeeds,
+        }
+        if gs.Finished() {
+            cgs.Fairness.ServerSeed = gs.ServerSeed
+            cgs.Fairness.Dealt = gs.Deck[:gs.DeckPos]
+        }
+    }
     b, m, f := Winners(gs.Hands, cgs.Faults)
     cgs.BackWinners, cgs.MiddleWinners, cgs.FrontWinners = b, m, f
+    if cgs.Finished {
+        cgs.Showdown = NewShowdown(gs.Hands)
+        value := float32(1)
+        if gs.Stakes != nil {
+            value = float32(gs.Stakes.PointValue)
+        }
+        for _, row := range cgs.Showdown.Payouts() {
+            payouts := make([]float32, len(row))
+            for j, points := range row {
+                payouts[j] = float32(points) * value
+            }
+            cgs.Payouts = append(cgs.Payouts, payouts)
+        }
+        for pos, winners := range [][]int{b, m, f} {
+            cgs.Explanations = append(cgs.Explanations,
+                explainRow(selectHands(gs.Hands, func(h *ChineseHand) *Hand { return h.Row(pos) }), winners, cgs.Faults))
+        }
+    }
     return cgs
 }
 
 func (gs *GameState) InGame(player string) bool {
-    for _, p := range gs.Players {
-        if p == player {
-            return true
+    return gs.seatOf(player) >= 0
+}
+
+// LiveCards returns the cards not yet placed in any hand.
+func (gs *GameState) LiveCards() []Card {
+    placed := make([]Card, 0)
+    for _, hand := range gs.Hands {
+        if hand != nil {
+            placed = append(placed, hand.Cards()...)
         }
     }
-    return false
+    return remaining(gs.Spec.Ordered(), placed)
 }
 
-func (gs *GameState) NewHand() {
-    gs.Deck = NewShuffledDeck()
-    gs.DeckPos = 0
-    gs.Hands = make([]*ChineseHand, 0)
-    for _, _ = range gs.Players {
-        gs.Hands = append(gs.Hands, &ChineseHand{})
+// liveTo is LiveCards as the player in seat knows them: cards placed where
+// they can't be seen may still be to come, as far as the player knows.
+func (gs *GameState) liveTo(seat int) []Card {
+    placed := make([]Card, 0)
+    for _, hand := range gs.Hands {
+        if hand == nil {
+            continue
+        }
+        for _, c := range hand.Cards() {
+            if gs.visible(c, seat) {
+                placed = append(placed, c)
+            }
+        }
+    }
+    return remaining(gs.Spec.Ordered(), placed)
+}
+
+// source is where the game's randomness comes from.  It is not saved with
+// the game, so a game that has been loaded falls back to CryptoSource.
+func (gs *GameState) source() RandSource {
+    if gs.src == nil {
+        return CryptoSource
+    }
+    return gs.src
+}
+
+// SetShuffler replaces the provably fair shuffle, e.g. with a PresetDeck for
+// a scripted scenario.  Like the RandSource it is not saved with the game.
+func (gs *GameState) SetShuffler(s Shuffler) {
+    gs.shuffler = s
+}
+
+// reseed picks a new secret server seed, whose commitment players see
+// before the hand is dealt.
+func (gs *GameState) reseed() error {
+    seed, err := NewServerSeed(gs.source())
+    if err != nil {
+        return err
     }
-    gs.Button = (gs.Button + 1)%len(gs.Hands)
+    gs.ServerSeed = seed
+    return nil
+}
+
+func (gs *GameState) NewHand(src RandSource) error {
+    if gs.Match != nil && gs.Match.Over {
+        return ErrMatchOver
+    }
+    gs.src = src
+    if err := gs.reseed(); err != nil {
+        return err
+    }
+    gs.keepHand()
+    gs.Moves = nil
+    gs.ScoresBefore = nil
+    // Seeds chosen before the new commitment could have been ground against,
+    // so every player adds a fresh one.
+    for i := range gs.ClientSeeds {
+        gs.ClientSeeds[i] = ""
+    }
+    gs.Seeded = nil
+    gs.Deck = nil
+    gs.DeckPos = 0
+    gs.deal()
+    // The button passes over empty seats and players sitting out.
+    gs.Button = gs.nextInHand(gs.Button)
     gs.Turn = gs.Button
     gs.Showing = nil
+    return nil
 }
 
 func (gs *GameState) Id() string {
@@ -100,29 +311,107 @@ func (gs *GameState) Id() string {
 }
 
 func (gs *GameState) Finished() bool {
-    return len(gs.Hands) > 0 && gs.Hands[gs.Turn].Count() == 13
+    return gs.Turn < len(gs.Hands) && gs.Hands[gs.Turn] != nil && gs.Hands[gs.Turn].Count() == 13
 }
 
-func (gs *GameState) Sit(id, name string) error {
-    if len(gs.Players) == 4 {
-        return errors.New("Game is full")
+const MaxSeats = 8
+
+// MaxPlayers is how many players the deck can deal 13 cards to, up to
+// MaxSeats.
+func (gs *GameState) MaxPlayers() int {
+    return min(MaxSeats, gs.Spec.Size() / 13)
+}
+
+// Sit adds a player.  seed is the player's contribution to the next shuffle,
+// and code the invite code, which a private table asks of anyone but its owner
+// and players with a reserved seat.
+func (gs *GameState) Sit(id, name, seed, code string) error {
+    if gs.Started() {
+        return ErrStarted
+    }
+    if err := gs.mayJoin(id, code); err != nil {
+        return err
+    }
+    gs.takeSeat(id, name, seed)
+    if has(gs.Spectators, id) {
+        gs.Watch(id)
+    }
+    return nil
+}
+
+// Seed sets the player's contribution to the next shuffle.  It can only be
+// given once the server has committed to its seed, and before the deal.
+func (gs *GameState) Seed(id, seed string) error {
+    seat := gs.seatOf(id)
+    if seat < 0 {
+        return ErrNotInGame
     }
     if gs.Started() {
-        return errors.New("Game has already started");
+        return ErrStarted
     }
-    gs.Players = append(gs.Players, id)
-    gs.PlayerNames = append(gs.PlayerNames, name)
-    gs.Hands = append(gs.Hands, &ChineseHand{})
+    gs.setSeed(seat, seed)
     return nil
 }
 
+func (gs *GameState) setSeed(seat int, seed string) {
+    for len(gs.Seeded) <= seat {
+        gs.Seeded = append(gs.Seeded, false)
+    }
+    gs.ClientSeeds[seat], gs.Seeded[seat] = seed, true
+}
+
+func (gs *GameState) seeded(seat int) bool {
+    return seat < len(gs.Seeded) && gs.Seeded[seat]
+}
+
+// needsSeed is whether the player id will be dealt into the next hand
+// without having added a seed for it.
+func (gs *GameState) needsSeed(id string) bool {
+    seat := gs.seatOf(id)
+    return seat >= 0 && !gs.Started() && gs.seated(seat) && !gs.seeded(seat)
+}
+
 func (gs *GameState) Started() bool {
     return gs.DeckPos > 0
 }
 
+// Start shuffles the deck from the server and client seeds and deals the
+// first cards.  Everyone dealt in must have added a seed for the hand.
+func (gs *GameState) Start() error {
+    if gs.Started() {
+        return ErrStarted
+    }
+    gs.deal()
+    if gs.inHand() == 0 {
+        return ErrNoPlayers
+    }
+    for i, p := range gs.Players {
+        if p != "" && gs.Hands[i] != nil && !gs.seeded(i) {
+            return ErrNoSeed
+        }
+    }
+    if gs.Hands[gs.Turn] == nil {
+        gs.Turn = gs.nextInHand(gs.Turn)
+    }
+    if gs.ServerSeed == "" {
+        if err := gs.reseed(); err != nil {
+            return err
+        }
+    }
+    shuffler := gs.shuffler
+    if shuffler == nil {
+        shuffler = FairShuffler{}
+    }
+    gs.Deck = shuffler.Shuffle(gs.Spec, gs.ServerSeed, gs.ClientSeeds)
+    gs.Moves = nil
+    gs.Undo = nil
+    gs.NextTurn()
+    return nil
+}
+
 func (gs *GameState) NextTurn() {
     if (gs.Started()) {
-        gs.Turn = (gs.Turn + 1) % len(gs.Hands)
+        gs.Turn = gs.nextInHand(gs.Turn)
     }
     n := 1
     c := gs.Hands[gs.Turn].Count()
@@ -133,21 +422,68 @@ func (gs *GameState) NextTurn() {
     }
     gs.Showing = gs.Deck[gs.DeckPos:gs.DeckPos + n]
     gs.DeckPos = gs.DeckPos + n
+    gs.startClock()
+}
+
+// Play places the idx'th showing card in row pos of the hand of player,
+// whose turn it must be, and moves on to the next player once every showing
+// card is placed.
+func (gs *GameState) Play(player string, idx, pos int) error {
+    if !gs.Started() || gs.Finished() {
+        return ErrNotInProgress
+    }
+    if gs.Players[gs.Turn] != player {
+        return ErrNotYourTurn
+    }
+    if idx < 0 || idx >= len(gs.Showing) {
+        return ErrInvalidPlay
+    }
+    if err := gs.Hands[gs.Turn].Place(pos, gs.Showing[idx]); err != nil {
+        return err
+    }
+    gs.Moves = append(gs.Moves, Move{Seat: gs.Turn, Card: gs.Showing[idx], Pos: pos, Dealt: gs.DeckPos,
+        Time: gs.now()})
+    gs.Undo = nil
+    // Showing is a window on the deck, so copy rather than shift it.
+    showing := make([]Card, 0, len(gs.Showing) - 1)
+    showing = append(showing, gs.Showing[:idx]...)
+    gs.Showing = append(showing, gs.Showing[idx+1:]...)
+    if len(gs.Showing) == 0 {
+        gs.stopClock()
+        gs.NextTurn()
+        if gs.Finished() {
+            gs.ScoresBefore = gs.scores()
+            gs.HandsPlayed++
+            gs.payStacks()
+            if gs.Match != nil {
+                gs.Match.Record(gs.Hands, gs.Players)
+            }
+        }
+    }
+    return nil
 }
 
 func (gs *GameState) Fix() {
     for _, hand := range gs.Hands {
-        hand.Fix()
+        if hand != nil {
+            hand.Fix()
+        }
     }
 }
 
-func NewGame(players int) *GameState {
-    d := NewShuffledDeck()
+func NewGame(players int, spec DeckSpec, src RandSource) (*GameState, error) {
+    if err := spec.Valid(); err != nil {
+        return nil, err
+    }
     h := make([]*ChineseHand, 0)
     for i := 0; i < players; i++ {
-        h = append(h, &ChineseHand{})
+        h = append(h, &ChineseHand{Low: spec.Low})
+    }
+    gs := &GameState{Spec: spec, Hands: h, src: src}
+    if err := gs.reseed(); err != nil {
+        return nil, err
     }
-    return &GameState{d, 0, h, 0, 0, nil, nil, nil, nil, nil}
+    return gs, nil
 }
 
 func (gs *GameState) Bytes() ([]byte, error) {
@@ -178,16 +514,145 @@ func fromBytes(b []byte) (*GameState, error) {
     return gs, nil
 }
 
+// GameData is how a game is stored.  Everything but Data is a summary kept
+// for the lobby to query.
 type GameData struct {
     Data []byte
+    Phase string
+    Players int
+    MaxPlayers int
+    Variant string
+    Rules string
+    PointValue float64
+    BuyIn int
+    Updated time.Time
+    Private bool
+    InviteCode string
+    Seats []string  // the players' ids
+    ToAct string    // the id of the player whose turn it is
+    Async bool
+}
+
+// Phases of a game, as listed in the lobby.
+const (
+    PhaseWaiting = "waiting"
+    PhasePlaying = "playing"
+    PhaseFinished = "finished"
+)
+
+func (gs *GameState) Phase() string {
+    switch {
+        case !gs.Started():
+            return PhaseWaiting
+        case gs.Finished() && (gs.Match == nil || gs.Match.Over):
+            return PhaseFinished
+    }
+    return PhasePlaying
+}
+
+// Rules describes how the game is scored.
+func (gs *GameState) Rules() string {
+    if gs.Match == nil {
+        return "Single hands"
+    }
+    return gs.Match.String()
+}
+
+func (gs *GameState) data(b []byte) *GameData {
+    gd := &GameData{
+        Data: b,
+        Phase: gs.Phase(),
+        Players: gs.occupied(),
+        MaxPlayers: gs.MaxPlayers(),
+        Variant: gs.Spec.String(),
+        Rules: gs.Rules(),
+        Updated: time.Now(),
+        Private: gs.Private,
+        InviteCode: gs.InviteCode,
+        Async: gs.Async,
+    }
+    for _, p := range gs.Players {
+        if p != "" {
+            gd.Seats = append(gd.Seats, p)
+        }
+    }
+    if gs.Started() && !gs.Finished() && len(gs.Showing) > 0 {
+        gd.ToAct = gs.Players[gs.Turn]
+    }
+    if gs.Stakes != nil {
+        gd.PointValue, gd.BuyIn = gs.Stakes.PointValue, gs.Stakes.BuyIn
+    }
+    return gd
+}
+
+// TableInfo is a game as the lobby lists it.
+type TableInfo struct {
+    Id string
+    Phase string
+    Players int
+    MaxPlayers int
+    Variant string
+    Rules string
+    PointValue float64
+    BuyIn int
+    Updated time.Time
+    Async bool
+    MyTurn bool
+}
+
+// List returns up to limit public games in the given phase, most recently
+// active first.
+func List(phase string, limit int, r *http.Request) ([]*TableInfo, error) {
+    c := appengine.NewContext(r)
+    q := datastore.NewQuery("GameState").Filter("Private =", false).Filter("Phase =", phase).
+        Order("-Updated").Limit(limit)
+    return tableInfo(c, q, "")
+}
+
+// MyGames returns up to limit games the player is seated at, most recently
+// active first.
+func MyGames(player string, limit int, r *http.Request) ([]*TableInfo, error) {
+    c := appengine.NewContext(r)
+    q := datastore.NewQuery("GameState").Filter("Seats =", player).Order("-Updated").Limit(limit)
+    return tableInfo(c, q, player)
+}
+
+func tableInfo(c appengine.Context, q *datastore.Query, player string) ([]*TableInfo, error) {
+    data := make([]*GameData, 0)
+    keys, err := q.GetAll(c, &data)
+    if err != nil {
+        return nil, err
+    }
+    tables := make([]*TableInfo, 0, len(data))
+    for i, gd := range data {
+        tables = append(tables, &TableInfo{
+            Id: keys[i].Encode(),
+            Phase: gd.Phase,
+            Players: gd.Players,
+            MaxPlayers: gd.MaxPlayers,
+            Variant: gd.Variant,
+            Rules: gd.Rules,
+            PointValue: gd.PointValue,
+            BuyIn: gd.BuyIn,
+            Updated: gd.Updated,
+            Async: gd.Async,
+            MyTurn: player != "" && gd.ToAct == player,
+        })
+    }
+    return tables, nil
 }
 
 func LoadGame(id string, r *http.Request) (*GameState, error) {
+    return LoadGameIn(appengine.NewContext(r), id)
+}
+
+// LoadGameIn loads the game with the context c, which inside a transaction
+// must be the transaction's.
+func LoadGameIn(c appengine.Context, id string) (*GameState, error) {
     key, err := datastore.DecodeKey(id)
     if err != nil {
         return nil, err
     }
-    c := appengine.NewContext(r)
     gd := &GameData{}
     if err = datastore.Get(c, key, gd); err != nil {
         return nil, err;
@@ -201,18 +666,23 @@ func LoadGame(id string, r *http.Request) (*GameState, error) {
 }
 
 func (gs *GameState) Save(r *http.Request) (error) {
-    c := appengine.NewContext(r)
+    return gs.SaveIn(appengine.NewContext(r))
+}
+
+// SaveIn saves the game with the context c, which inside a transaction must
+// be the transaction's.
+func (gs *GameState) SaveIn(c appengine.Context) error {
     b, err := gs.Bytes()
     if err != nil {
         return err
     }
     if gs.key != nil {
-        _, err := datastore.Put(c, gs.key, &GameData{b})
+        _, err := datastore.Put(c, gs.key, gs.data(b))
         if err != nil {
             return err
         }
     } else {
-        key, err := datastore.Put(c, datastore.NewIncompleteKey(c, "GameState", nil), &GameData{b})
+        key, err := datastore.Put(c, datastore.NewIncompleteKey(c, "GameState", nil), gs.data(b))
         if err != nil {
             return err
         }
@@ -0,0 +1,75 @@
+package poker
+
+import (
+    "testing"
+)
+
+func TestPhase(t *testing.T) {
+    gs, err := NewGame(0, DeckSpec{Low: 4, Decks: 2, Jokers: 1}, SeededSource(1))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if gs.Match, err = NewMatch(FixedHands, 1); err != nil {
+        t.Fatal(err)
+    }
+    gs.Sit("a", "A", "", "")
+    gs.Sit("b", "B", "", "")
+    if p := gs.Phase(); p != PhaseWaiting {
+        t.Errorf("before the start: %s", p)
+    }
+    gs.Start()
+    if p := gs.Phase(); p != PhasePlaying {
+        t.Errorf("after the start: %s", p)
+    }
+    playOut(t, gs)
+    if p := gs.Phase(); p != PhaseFinished {
+        t.Errorf("after the match: %s", p)
+    }
+    gd := gs.data(nil)
+    if gd.Players != 2 || gd.Variant != "Short deck x2, 1 joker" || gd.Rules != "Match of 1 hand" {
+        t.Errorf("stored %+v", gd)
+    }
+}
+
+func TestSeeds(t *testing.T) {
+    gs, err := NewGame(0, StandardDeck, SeededSource(2))
+    if err != nil {
+        t.Fatal(err)
+    }
+    gs.Sit("a", "A", "first", "")
+    gs.Sit("b", "B", "", "")
+    if err := gs.Start(); err != nil {
+        t.Fatal(err)
+    }
+    if err := gs.Seed("a", "late"); err != ErrStarted {
+        t.Errorf("seeded a hand already dealt: %v", err)
+    }
+    playOut(t, gs)
+    if err := gs.NewHand(SeededSource(3)); err != nil {
+        t.Fatal(err)
+    }
+    // Last hand's seeds were chosen before this hand's commitment.
+    if !gs.ClientState("a").NeedSeed || gs.ClientSeeds[0] != "" {
+        t.Errorf("kept seeds %v", gs.ClientSeeds)
+    }
+    if err := gs.Seed("a", "second"); err != nil {
+        t.Fatal(err)
+    }
+    if err := gs.Start(); err != ErrNoSeed {
+        t.Errorf("started without b's seed: %v", err)
+    }
+    if err := gs.Seed("c", "x"); err != ErrNotInGame {
+        t.Errorf("seeded by someone not playing: %v", err)
+    }
+    gs.Seed("b", "")
+    if gs.ClientState("b").NeedSeed {
+        t.Error("an empty seed was not taken")
+    }
+    if err := gs.Start(); err != nil {
+        t.Fatal(err)
+    }
+    want := StandardDeck.SeededShuffle(gs.ServerSeed, []string{"second", ""})
+    if gs.Deck[0] != want[0] || gs.Deck[51] != want[51] {
+        t.Error("the deck did not follow this hand's seeds")
+    }
+}
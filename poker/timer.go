@@ -0,0 +1,117 @@
+package poker
+
+import (
+    "errors"
+    "time"
+)
+
+// autoSamples is how many completions the auto-player draws when it weighs
+// a row; it only needs to tell rows apart, not report the odds.
+const autoSamples = 300
+
+// SetTimer gives each player turn to place a street's cards, and a bank of
+// extra time that is drawn down whenever they go over.  A zero turn leaves
+// the table untimed.
+func (gs *GameState) SetTimer(turn, bank time.Duration) error {
+    if turn < 0 || bank < 0 {
+        return errors.New("Times can't be negative")
+    }
+    if turn == 0 {
+        bank = 0
+    }
+    gs.TurnTime, gs.TimeBank = turn, bank
+    return nil
+}
+
+func (gs *GameState) Timed() bool {
+    return gs.TurnTime > 0
+}
+
+// now is the time the clock reads.  Like the RandSource, the clock is not
+// saved with the game.
+func (gs *GameState) now() time.Time {
+    if gs.clock == nil {
+        return time.Now()
+    }
+    return gs.clock()
+}
+
+func (gs *GameState) bank(seat int) time.Duration {
+    for len(gs.Banks) <= seat {
+        gs.Banks = append(gs.Banks, gs.TimeBank)
+    }
+    return gs.Banks[seat]
+}
+
+// startClock starts the turn's clock, or stops it once the hand is over.
+func (gs *GameState) startClock() {
+    if !gs.Timed() || gs.Finished() {
+        gs.Deadline = time.Time{}
+        return
+    }
+    gs.TurnStart = gs.now()
+    gs.Deadline = gs.TurnStart.Add(gs.TurnTime + gs.bank(gs.Turn))
+}
+
+// stopClock charges the time the turn took beyond TurnTime to the player's
+// bank.
+func (gs *GameState) stopClock() {
+    if !gs.Timed() || gs.TurnStart.IsZero() {
+        return
+    }
+    if over := gs.now().Sub(gs.TurnStart) - gs.TurnTime; over > 0 {
+        left := gs.bank(gs.Turn) - over
+        if left < 0 {
+            left = 0
+        }
+        gs.Banks[gs.Turn] = left
+    }
+}
+
+// TimedOut is whether the player to act has run out of time.
+func (gs *GameState) TimedOut() bool {
+    return gs.Timed() && !gs.Deadline.IsZero() && gs.Started() && !gs.Finished() &&
+        !gs.now().Before(gs.Deadline)
+}
+
+// AutoPlay places the showing cards for a player who has timed out, each
+// in the row that leaves the hand least likely to foul.
+func (gs *GameState) AutoPlay() error {
+    if !gs.TimedOut() {
+        return errors.New("Turn has not timed out")
+    }
+    player := gs.Players[gs.Turn]
+    for n := len(gs.Showing); n > 0; n-- {
+        pos, err := gs.Hands[gs.Turn].SafeRow(gs.Showing[0], gs.LiveCards(), gs.source())
+        if err != nil {
+            return err
+        }
+        if err := gs.Play(player, 0, pos); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// SafeRow picks the row to place c in: of the rows with room, the one with
+// the lowest chance of fouling, the back first among equals.
+func (ch *ChineseHand) SafeRow(c Card, live []Card, src RandSource) (int, error) {
+    best, bestOdds := -1, 2.0
+    for pos := Back; pos <= Front; pos++ {
+        try := *ch
+        if try.Place(pos, c) != nil {
+            continue
+        }
+        odds, err := try.Odds(remaining(live, []Card{c}), autoSamples, src)
+        if err != nil {
+            return 0, err
+        }
+        if odds.Probability < bestOdds {
+            best, bestOdds = pos, odds.Probability
+        }
+    }
+    if best < 0 {
+        return 0, errors.New("Hand is full")
+    }
+    return best, nil
+}
@@ -0,0 +1,74 @@
+package poker
+
+import (
+    "testing"
+    "time"
+)
+
+func TestTimer(t *testing.T) {
+    gs, err := NewGame(0, StandardDeck, SeededSource(8))
+    if err != nil {
+        t.Fatal(err)
+    }
+    now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+    gs.clock = func() time.Time { return now }
+    if err := gs.SetTimer(30 * time.Second, time.Minute); err != nil {
+        t.Fatal(err)
+    }
+    gs.Sit("a", "A", "", "")
+    gs.Sit("b", "B", "", "")
+    if err := gs.Start(); err != nil {
+        t.Fatal(err)
+    }
+    if want := now.Add(90 * time.Second); !gs.Deadline.Equal(want) {
+        t.Errorf("deadline %v, want %v", gs.Deadline, want)
+    }
+    if err := gs.AutoPlay(); err == nil {
+        t.Errorf("auto-played before the deadline")
+    }
+    // a takes 50 seconds, 20 of them from the bank.
+    now = now.Add(50 * time.Second)
+    for i := 0; i < 5; i++ {
+        gs.Play("a", 0, i % 3)
+    }
+    if gs.Turn != 1 || gs.Banks[0] != 40 * time.Second {
+        t.Fatalf("turn %d, banks %v", gs.Turn, gs.Banks)
+    }
+    now = now.Add(90 * time.Second)
+    if !gs.TimedOut() {
+        t.Fatalf("b did not time out")
+    }
+    if err := gs.AutoPlay(); err != nil {
+        t.Fatal(err)
+    }
+    if gs.Turn != 0 || gs.Hands[1].Count() != 5 || gs.Banks[1] != 0 {
+        t.Errorf("after auto-play: turn %d, %d cards, banks %v", gs.Turn, gs.Hands[1].Count(), gs.Banks)
+    }
+    if want := now.Add(70 * time.Second); !gs.Deadline.Equal(want) {
+        t.Errorf("deadline %v, want %v", gs.Deadline, want)
+    }
+    for !gs.Finished() {
+        now = now.Add(2 * time.Minute)
+        if err := gs.AutoPlay(); err != nil {
+            t.Fatal(err)
+        }
+    }
+    if !gs.Deadline.IsZero() || gs.TimedOut() {
+        t.Errorf("clock still running after the hand: %v", gs.Deadline)
+    }
+}
+
+func TestSafeRow(t *testing.T) {
+    // With a pair of aces in front, a third ace there would all but foul.
+    ch := &ChineseHand{}
+    aces, _ := ParseCards("AsAhAd")
+    ch.Place(Front, aces[0])
+    ch.Place(Front, aces[1])
+    pos, err := ch.SafeRow(aces[2], remaining(StandardDeck.Ordered(), aces[:2]), SeededSource(1))
+    if err != nil {
+        t.Fatal(err)
+    }
+    if pos == Front {
+        t.Errorf("put the third ace in front")
+    }
+}
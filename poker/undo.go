@@ -0,0 +1,178 @@
+package poker
+
+import (
+    "errors"
+)
+
+// How far a player can take back their placements.
+const (
+    UndoTurn = iota  // only while they still have cards showing
+    UndoConsent      // further back too, if the other players agree
+    UndoNone
+)
+
+// UndoRequest is a player asking to take back their last placement after
+// their turn is over.  Every other player in the hand must approve it, and
+// it lapses if anyone plays on first.
+type UndoRequest struct {
+    Seat int
+    Move int     // index in Moves of the placement to take back
+    Approved []int
+}
+
+// CanTakeBack is whether player may take back a placement without asking:
+// it is their turn, and the card was placed this turn, so putting it back
+// among the showing cards reveals nothing new.
+func (gs *GameState) CanTakeBack(player string) bool {
+    if gs.UndoPolicy == UndoNone || !gs.Started() || gs.Finished() || len(gs.Moves) == 0 {
+        return false
+    }
+    last := gs.Moves[len(gs.Moves) - 1]
+    return gs.Players[gs.Turn] == player && last.Seat == gs.Turn && last.Dealt == gs.DeckPos
+}
+
+// TakeBack returns the player's last placement this turn to the showing
+// cards.
+func (gs *GameState) TakeBack(player string) error {
+    if !gs.CanTakeBack(player) {
+        return errors.New("Nothing to take back")
+    }
+    last := gs.Moves[len(gs.Moves) - 1]
+    gs.Hands[last.Seat].unplace(last.Pos, last.Card)
+    gs.Moves = gs.Moves[:len(gs.Moves) - 1]
+    gs.Showing = append(append([]Card{}, gs.Showing...), last.Card)
+    gs.Undo = nil
+    return nil
+}
+
+// unplace removes c from the row at pos.
+func (ch *ChineseHand) unplace(pos int, c Card) {
+    cards := make([]Card, 0)
+    removed := false
+    for _, card := range ch.Row(pos).Cards() {
+        if card == c && !removed {
+            removed = true
+            continue
+        }
+        cards = append(cards, card)
+    }
+    if len(cards) == 0 {
+        ch.setRow(pos, nil)
+    } else {
+        ch.setRow(pos, newHand(cards, ch.Low))
+    }
+}
+
+// RequestUndo asks the other players to let player take back their last
+// placement, and everything played since.  The deck is not shuffled again,
+// so once player has been dealt more cards they cannot go back past them.
+func (gs *GameState) RequestUndo(player string) error {
+    if gs.CanTakeBack(player) {
+        return gs.TakeBack(player)
+    }
+    if gs.UndoPolicy != UndoConsent {
+        return errors.New("This table does not allow undoing past your turn")
+    }
+    if !gs.Started() || gs.Finished() {
+        return ErrNotInProgress
+    }
+    if gs.Undo != nil {
+        return errors.New("An undo is already waiting for approval")
+    }
+    seat := gs.seatOf(player)
+    move := -1
+    for i := len(gs.Moves) - 1; i >= 0 && seat >= 0; i-- {
+        if gs.Moves[i].Seat == seat {
+            move = i
+            break
+        }
+    }
+    if move < 0 {
+        return errors.New("Nothing to take back")
+    }
+    if gs.Turn == seat && gs.Moves[move].Dealt != gs.DeckPos {
+        return errors.New("You can't undo once you have seen your next cards")
+    }
+    gs.Undo = &UndoRequest{Seat: seat, Move: move}
+    return gs.settleUndo()
+}
+
+// AnswerUndo approves or rejects the waiting undo request.
+func (gs *GameState) AnswerUndo(player string, approve bool) error {
+    seat := gs.seatOf(player)
+    if gs.Undo == nil {
+        return errors.New("No undo is waiting")
+    }
+    if seat < 0 || seat == gs.Undo.Seat || gs.Hands[seat] == nil {
+        return errors.New("You can't answer this undo")
+    }
+    if !approve {
+        gs.Undo = nil
+        return nil
+    }
+    for _, s := range gs.Undo.Approved {
+        if s == seat {
+            return nil
+        }
+    }
+    gs.Undo.Approved = append(gs.Undo.Approved, seat)
+    return gs.settleUndo()
+}
+
+// settleUndo carries out the undo once every other player in the hand has
+// approved it.
+func (gs *GameState) settleUndo() error {
+    u := gs.Undo
+    for i, hand := range gs.Hands {
+        if hand == nil || i == u.Seat {
+            continue
+        }
+        approved := false
+        for _, s := range u.Approved {
+            approved = approved || s == i
+        }
+        if !approved {
+            return nil
+        }
+    }
+    gs.Undo = nil
+    return gs.replay(gs.Moves[:u.Move])
+}
+
+// replay deals the hand again from the start and plays moves, leaving the
+// game as it stood after them.  The deck is already shuffled, so every card
+// comes out where it did before.
+func (gs *GameState) replay(moves []Move) error {
+    moves = append([]Move{}, moves...)
+    if len(gs.Moves) > 0 {
+        gs.Turn = gs.Moves[0].Seat
+    }
+    for i, hand := range gs.Hands {
+        if hand != nil {
+            gs.Hands[i] = &ChineseHand{Low: gs.Spec.Low}
+        }
+    }
+    gs.DeckPos = 0
+    gs.Showing = nil
+    gs.Moves = nil
+    gs.NextTurn()
+    for _, m := range moves {
+        idx := -1
+        for i, c := range gs.Showing {
+            if c == m.Card {
+                idx = i
+            }
+        }
+        if idx < 0 || m.Seat != gs.Turn {
+            return errors.New("The game's history does not replay")
+        }
+        if err := gs.Play(gs.Players[m.Seat], idx, m.Pos); err != nil {
+            return err
+        }
+    }
+    // The moves happened when they first did, as spectators see them.
+    for i := range gs.Moves {
+        gs.Moves[i].Time = moves[i].Time
+    }
+    return nil
+}
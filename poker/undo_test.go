@@ -0,0 +1,139 @@
+package poker
+
+import (
+    "testing"
+)
+
+func undoGame(t *testing.T, policy int) *GameState {
+    gs, err := NewGame(0, StandardDeck, SeededSource(12))
+    if err != nil {
+        t.Fatal(err)
+    }
+    gs.UndoPolicy = policy
+    gs.Sit("a", "A", "", "")
+    gs.Sit("b", "B", "", "")
+    gs.Sit("c", "C", "", "")
+    if err := gs.Start(); err != nil {
+        t.Fatal(err)
+    }
+    return gs
+}
+
+// playTurn places every showing card, the first two in back and the rest in
+// the middle.
+func playTurn(t *testing.T, gs *GameState) {
+    player := gs.Players[gs.Turn]
+    n := len(gs.Showing)
+    for i := 0; i < n; i++ {
+        pos := Back
+        if i >= 2 {
+            pos = Middle
+        }
+        if err := gs.Play(player, 0, pos); err != nil {
+            t.Fatal(err)
+        }
+    }
+}
+
+func TestTakeBack(t *testing.T) {
+    gs := undoGame(t, UndoTurn)
+    player := gs.Players[gs.Turn]
+    first, second := gs.Showing[0], gs.Showing[1]
+    gs.Play(player, 0, Back)
+    gs.Play(player, 0, Back)
+    if err := gs.TakeBack(player); err != nil {
+        t.Fatal(err)
+    }
+    hand := gs.Hands[gs.Turn]
+    if hand.Count() != 1 || hand.Back.Cards()[0] != first || len(gs.Moves) != 1 {
+        t.Errorf("after taking back, hand %v, moves %v", hand.Cards(), gs.Moves)
+    }
+    if len(gs.Showing) != 4 || gs.Showing[3] != second {
+        t.Errorf("card taken back is not showing: %v", gs.Showing)
+    }
+    if gs.CanTakeBack(gs.Players[gs.nextInHand(gs.Turn)]) {
+        t.Errorf("another player could take back the card")
+    }
+
+    playTurn(t, gs)
+    if gs.CanTakeBack(player) {
+        t.Errorf("could take back a card once the turn was over")
+    }
+    if err := gs.RequestUndo(player); err == nil {
+        t.Errorf("asked to undo on a table that doesn't allow it")
+    }
+}
+
+func TestUndoNone(t *testing.T) {
+    gs := undoGame(t, UndoNone)
+    player := gs.Players[gs.Turn]
+    gs.Play(player, 0, Back)
+    if err := gs.TakeBack(player); err == nil {
+        t.Errorf("took back a card on a table without undo")
+    }
+}
+
+func TestUndoConsent(t *testing.T) {
+    gs := undoGame(t, UndoConsent)
+    seat := gs.Turn
+    player := gs.Players[seat]
+    playTurn(t, gs)
+    last := gs.Moves[len(gs.Moves) - 1]
+    next := gs.Turn
+    gs.Play(gs.Players[next], 0, Front)
+    gs.Play(gs.Players[next], 0, Front)
+    third := gs.nextInHand(next)
+
+    if err := gs.RequestUndo(player); err != nil {
+        t.Fatal(err)
+    }
+    if err := gs.AnswerUndo(player, true); err == nil {
+        t.Errorf("approved their own undo")
+    }
+    if err := gs.AnswerUndo(gs.Players[third], false); err != nil {
+        t.Fatal(err)
+    }
+    if gs.Undo != nil {
+        t.Errorf("undo still waiting after it was refused")
+    }
+
+    gs.RequestUndo(player)
+    gs.AnswerUndo(gs.Players[next], true)
+    if gs.Turn != next || len(gs.Moves) != 7 {
+        t.Errorf("undo happened before everyone approved")
+    }
+    if err := gs.AnswerUndo(gs.Players[third], true); err != nil {
+        t.Fatal(err)
+    }
+    if gs.Undo != nil || gs.Turn != seat || len(gs.Showing) != 1 || gs.Showing[0] != last.Card {
+        t.Errorf("after undo, turn %d showing %v", gs.Turn, gs.Showing)
+    }
+    if len(gs.Moves) != 4 || gs.Hands[seat].Count() != 4 || gs.Hands[next].Count() != 0 {
+        t.Errorf("after undo, moves %v", gs.Moves)
+    }
+
+    // Playing on again deals what was dealt before.
+    gs.Play(player, 0, Front)
+    if gs.Turn != next || len(gs.Showing) != 5 {
+        t.Errorf("after playing on, turn %d showing %v", gs.Turn, gs.Showing)
+    }
+    gs.RequestUndo(player)
+    gs.Play(gs.Players[next], 0, Back)
+    if gs.Undo != nil {
+        t.Errorf("undo request outlived the next play")
+    }
+}
+
+func TestUndoPastDeal(t *testing.T) {
+    gs := undoGame(t, UndoConsent)
+    player := gs.Players[gs.Turn]
+    for i := 0; i < 3; i++ {
+        playTurn(t, gs)
+    }
+    if gs.Players[gs.Turn] != player || len(gs.Showing) != 1 {
+        t.Fatalf("turn %d showing %v", gs.Turn, gs.Showing)
+    }
+    if err := gs.RequestUndo(player); err == nil || gs.Undo != nil {
+        t.Errorf("asked to undo past cards already dealt to them")
+    }
+}
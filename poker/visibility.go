@@ -0,0 +1,116 @@
+package poker
+
+// Hidden stands in for a card the viewer may not see.  It is below every
+// joker, so it is never a card of any deck.
+const Hidden Card = -64
+
+// When a card is shown to players other than its owner.
+const (
+    RevealAlways = iota  // as soon as it is placed
+    RevealShowdown       // once the hand is over
+)
+
+// Visibility is who may see a card: its owner always, everyone else once
+// Reveal allows.  Owner is a seat, or -1 for a card nobody holds.
+type Visibility struct {
+    Owner int
+    Reveal int
+}
+
+// To is whether the player in seat may see the card.  seat is -1 for a
+// spectator.
+func (v Visibility) To(seat int, finished bool) bool {
+    switch {
+        case v.Owner >= 0 && v.Owner == seat:
+            return true
+        case v.Reveal == RevealAlways:
+            return true
+    }
+    return v.Reveal == RevealShowdown && finished
+}
+
+// Visibility says who may see c: a placed card is its seat's, and shown to
+// the others as PlacedReveal says; a card dealt but not yet placed is only
+// the acting player's; the rest of the deck is nobody's until the server
+// seed, and with it the whole shuffle, is revealed at the end of the hand.
+func (gs *GameState) Visibility(c Card) Visibility {
+    for i, hand := range gs.Hands {
+        if hand != nil && contains(hand.Cards(), c) {
+            return Visibility{Owner: i, Reveal: gs.PlacedReveal}
+        }
+    }
+    if contains(gs.Showing, c) {
+        return Visibility{Owner: gs.Turn, Reveal: RevealShowdown}
+    }
+    return Visibility{Owner: -1, Reveal: RevealShowdown}
+}
+
+func (gs *GameState) visible(c Card, seat int) bool {
+    return gs.Visibility(c).To(seat, gs.Finished())
+}
+
+// hiddenHand has a row's shape but none of its cards.
+func hiddenHand(h *Hand) *Hand {
+    if h == nil {
+        return nil
+    }
+    cards := make([]Card, h.Count())
+    for i := range cards {
+        cards[i] = Hidden
+    }
+    return &Hand{Royalty: Royalty{Cards: cards}, Low: h.Low}
+}
+
+// redact replaces every card in cgs that the player in seat may not see
+// with Hidden, and drops whatever would give such a card away: a hidden
+// hand's description, royalties and foul, and the results of the hand.
+func (gs *GameState) redact(cgs *ClientGameState, seat int) {
+    if len(cgs.Showing) > 0 {
+        showing := make([]Card, len(cgs.Showing))
+        for i, c := range cgs.Showing {
+            showing[i] = c
+            if !gs.visible(c, seat) {
+                showing[i] = Hidden
+            }
+        }
+        cgs.Showing = showing
+    }
+    hands := make([]*ChineseHand, len(cgs.Hands))
+    copy(hands, cgs.Hands)
+    redacted := false
+    for i, hand := range hands {
+        if hand == nil {
+            continue
+        }
+        for _, c := range hand.Cards() {
+            if !gs.visible(c, seat) {
+                hands[i] = &ChineseHand{Back: hiddenHand(hand.Back), Middle: hiddenHand(hand.Middle),
+                    Front: hiddenHand(hand.Front), Low: hand.Low}
+                redacted = true
+                if i < len(cgs.Descriptions) {
+                    cgs.Descriptions[i] = nil
+                }
+                if i < len(cgs.Royalties) {
+                    cgs.Royalties[i] = 0
+                }
+                if i < len(cgs.Faults) {
+                    cgs.Faults[i] = false
+                }
+                break
+            }
+        }
+    }
+    cgs.Hands = hands
+    if redacted {
+        cgs.BackWinners, cgs.MiddleWinners, cgs.FrontWinners = nil, nil, nil
+        cgs.Explanations, cgs.Showdown, cgs.Payouts = nil, nil, nil
+    }
+    if f := cgs.Fairness; f != nil && len(f.Dealt) > 0 {
+        for _, c := range f.Dealt {
+            if !gs.visible(c, seat) {
+                cgs.Fairness = &Fairness{Spec: f.Spec, Commitment: f.Commitment, ClientSeeds: f.ClientSeeds}
+                break
+            }
+        }
+    }
+}
@@ -0,0 +1,143 @@
+package poker
+
+import (
+    "encoding/json"
+    "fmt"
+    "reflect"
+    "testing"
+)
+
+var cardType = reflect.TypeOf(Card(0))
+
+// cardsIn collects every Card anywhere in v.
+func cardsIn(v reflect.Value, out *[]Card) {
+    switch v.Kind() {
+        case reflect.Ptr, reflect.Interface:
+            if !v.IsNil() {
+                cardsIn(v.Elem(), out)
+            }
+        case reflect.Struct:
+            for i := 0; i < v.NumField(); i++ {
+                cardsIn(v.Field(i), out)
+            }
+        case reflect.Slice, reflect.Array:
+            for i := 0; i < v.Len(); i++ {
+                cardsIn(v.Index(i), out)
+            }
+        case reflect.Map:
+            for _, k := range v.MapKeys() {
+                cardsIn(v.MapIndex(k), out)
+            }
+        default:
+            if v.Type() == cardType {
+                *out = append(*out, Card(v.Int()))
+            }
+    }
+}
+
+// allowed is, worked out apart from the redaction, every card the player in
+// seat may know of.
+func allowed(gs *GameState, seat int) map[Card]bool {
+    ok := map[Card]bool{Hidden: true}
+    if gs.Finished() {
+        for _, c := range gs.Deck[:gs.DeckPos] {
+            ok[c] = true
+        }
+    }
+    if seat >= 0 && seat == gs.Turn {
+        for _, c := range gs.Showing {
+            ok[c] = true
+        }
+    }
+    for i, hand := range gs.Hands {
+        if hand != nil && (i == seat || gs.PlacedReveal == RevealAlways) {
+            for _, c := range hand.Cards() {
+                ok[c] = true
+            }
+            // The cards jokers stand for give away nothing the jokers don't.
+            for pos := Back; pos <= Front; pos++ {
+                if row := hand.Row(pos); row != nil {
+                    for _, c := range row.Subs {
+                        ok[c] = true
+                    }
+                }
+            }
+        }
+    }
+    return ok
+}
+
+// checkLeaks fails if any viewer's JSON holds a card they may not see.
+func checkLeaks(t *testing.T, gs *GameState, viewers []string) {
+    for _, id := range viewers {
+        s, err := gs.ClientState(id).JSON()
+        if err != nil {
+            t.Fatal(err)
+        }
+        var cgs ClientGameState
+        if err := json.Unmarshal([]byte(s), &cgs); err != nil {
+            t.Fatal(err)
+        }
+        cards := make([]Card, 0)
+        cardsIn(reflect.ValueOf(cgs), &cards)
+        ok := allowed(gs, gs.seatOf(id))
+        for _, c := range cards {
+            if !ok[c] {
+                t.Fatalf("%s was sent %v at deck position %d", id, c, gs.DeckPos)
+            }
+        }
+    }
+}
+
+func TestNoLeaks(t *testing.T) {
+    for _, reveal := range []int{RevealAlways, RevealShowdown} {
+        for seed := int64(0); seed < 6; seed++ {
+            gs, err := NewGame(0, DeckSpec{Decks: 1, Jokers: int(seed / 5)}, SeededSource(seed))
+            if err != nil {
+                t.Fatal(err)
+            }
+            gs.PlacedReveal = reveal
+            viewers := []string{"spectator"}
+            for i := 0; i < 2 + int(seed % 3); i++ {
+                id := fmt.Sprint("p", i)
+                gs.Sit(id, id, "", "")
+                viewers = append(viewers, id)
+            }
+            gs.Start()
+            for step := 0; !gs.Finished(); step++ {
+                checkLeaks(t, gs, viewers)
+                for pos := (step + int(seed)) % 3; gs.Play(gs.Players[gs.Turn], 0, pos) != nil; pos = (pos + 1) % 3 {
+                }
+            }
+            checkLeaks(t, gs, viewers)
+        }
+    }
+}
+
+func TestRedaction(t *testing.T) {
+    gs, err := NewGame(0, StandardDeck, SeededSource(11))
+    if err != nil {
+        t.Fatal(err)
+    }
+    gs.PlacedReveal = RevealShowdown
+    gs.Sit("a", "A", "", "")
+    gs.Sit("b", "B", "", "")
+    gs.Start()
+    for i := 0; i < 5; i++ {
+        gs.Play("a", 0, i % 3)
+    }
+    mine, theirs := gs.ClientState("a"), gs.ClientState("b")
+    if mine.Hands[0].Back.Royalty.Cards[0] == Hidden || mine.Descriptions[0] == nil {
+        t.Errorf("a's own hand was hidden from a")
+    }
+    if theirs.Hands[0].Back.Royalty.Cards[0] != Hidden || theirs.Hands[0].Count() != 5 || theirs.Descriptions[0] != nil {
+        t.Errorf("b saw %v", theirs.Hands[0].Cards())
+    }
+    if mine.Showing[0] != Hidden || theirs.Showing[0] == Hidden {
+        t.Errorf("showing to a %v, to b %v", mine.Showing, theirs.Showing)
+    }
+    playOut(t, gs)
+    if cgs := gs.ClientState("b"); cgs.Hands[0].Back.Royalty.Cards[0] == Hidden || cgs.Showdown == nil {
+        t.Errorf("hands still hidden after the showdown")
+    }
+}
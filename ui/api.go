@@ -0,0 +1,353 @@
+package ui
+
+import (
+    "appengine"
+    "appengine/datastore"
+    "appengine/user"
+    "encoding/json"
+    "errors"
+    "io"
+    "net/http"
+    "net/url"
+    "poker"
+    "reflect"
+    "strconv"
+    "strings"
+)
+
+// The JSON API.  Requests and responses are JSON; a request that fails gets
+// an APIError with a code to tell failures apart by, and one that succeeds
+// the game as the caller now sees it.
+const apiPrefix = "/api/v1"
+
+type APIError struct {
+    Code string
+    Message string
+    status int
+}
+
+func (e *APIError) Error() string {
+    return e.Message
+}
+
+type apiRule struct {
+    status int
+    code string
+}
+
+// ruleErrors are the status and code for each way a player can break the
+// game's rules.
+var ruleErrors = map[error]apiRule{
+    poker.ErrStarted: {http.StatusConflict, "already_started"},
+    poker.ErrNotInProgress: {http.StatusConflict, "not_in_progress"},
+    poker.ErrNotYourTurn: {http.StatusConflict, "not_your_turn"},
+    poker.ErrInvalidPlay: {http.StatusBadRequest, "invalid_play"},
+    poker.ErrNoPlayers: {http.StatusConflict, "no_players"},
+    poker.ErrMatchOver: {http.StatusConflict, "match_over"},
+    poker.ErrNotInGame: {http.StatusForbidden, "not_in_game"},
+    poker.ErrAlreadySitting: {http.StatusConflict, "already_sitting"},
+    poker.ErrHandInProgress: {http.StatusConflict, "hand_in_progress"},
+    poker.ErrLocked: {http.StatusForbidden, "table_locked"},
+    poker.ErrNeedsCode: {http.StatusForbidden, "invite_code_required"},
+    poker.ErrFull: {http.StatusConflict, "table_full"},
+    poker.ErrNotOwner: {http.StatusForbidden, "not_owner"},
+    poker.ErrNoSeed: {http.StatusConflict, "seed_required"},
+    errNameRequired: {http.StatusBadRequest, "name_required"},
+}
+
+var errNameRequired = errors.New("Please choose a name")
+
+var errNoSuchGame = &APIError{"no_such_game", "No such game", http.StatusNotFound}
+
+// rejected is err, from the game refusing a request, as an APIError.
+// Errors it has no rule for are problems with the request.
+func rejected(err error) error {
+    if _, ok := err.(*APIError); ok {
+        return err
+    }
+    if rule, ok := ruleErrors[err]; ok {
+        return &APIError{rule.code, err.Error(), rule.status}
+    }
+    return &APIError{"invalid", err.Error(), http.StatusBadRequest}
+}
+
+// failed is err as an APIError.  Errors not already rejected by the game are
+// the server's fault.
+func failed(err error) *APIError {
+    if e, ok := err.(*APIError); ok {
+        return e
+    }
+    if err == datastore.ErrConcurrentTransaction {
+        return &APIError{"busy", "The game changed while saving; try again", http.StatusConflict}
+    }
+    return &APIError{"internal", err.Error(), http.StatusInternalServerError}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+    w.Header().Set("Content-type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(v)
+}
+
+func apiFail(w http.ResponseWriter, err error) {
+    e := failed(err)
+    writeJSON(w, e.status, e)
+}
+
+// decodeBody reads the request's JSON body into v.  An empty body leaves v
+// as it was.
+func decodeBody(r *http.Request, v interface{}) error {
+    if r.Body == nil {
+        return nil
+    }
+    if err := json.NewDecoder(r.Body).Decode(v); err != nil && err != io.EOF {
+        return &APIError{"bad_json", "Bad JSON: " + err.Error(), http.StatusBadRequest}
+    }
+    return nil
+}
+
+// allow checks the request's method, answering 405 if it is not method.
+func allow(w http.ResponseWriter, r *http.Request, method string) bool {
+    if r.Method == method {
+        return true
+    }
+    w.Header().Set("Allow", method)
+    writeJSON(w, http.StatusMethodNotAllowed,
+        &APIError{"method_not_allowed", "Use " + method, http.StatusMethodNotAllowed})
+    return false
+}
+
+// CreateRequest has the fields of the lobby's create form.  Zero values
+// take the form's defaults.
+type CreateRequest struct {
+    Deck string        // "standard" or "short"
+    Decks, Jokers int
+    Match string       // "", "open", "hands" or "points"
+    Target int
+    Value float64      // what a point is worth; 0 for no stakes
+    BuyIn int
+    Turn, Bank int     // seconds
+    Spectators string  // "placed", "after" or "none"
+    Delay int          // seconds
+    Undo string        // "turn", "consent" or "none"
+    Reveal string      // "placed" or "showdown"
+    Async bool
+    Move int           // hours
+    Private bool
+}
+
+// form is the request as the create form would have sent it.
+func (cr *CreateRequest) form() url.Values {
+    v := url.Values{}
+    set := func(name, value string) {
+        if value != "" && value != "0" && value != "false" {
+            v.Set(name, value)
+        }
+    }
+    set("deck", cr.Deck)
+    set("decks", strconv.Itoa(cr.Decks))
+    set("jokers", strconv.Itoa(cr.Jokers))
+    set("match", cr.Match)
+    set("target", strconv.Itoa(cr.Target))
+    set("value", strconv.FormatFloat(cr.Value, 'f', -1, 64))
+    set("buyin", strconv.Itoa(cr.BuyIn))
+    set("turn", strconv.Itoa(cr.Turn))
+    set("bank", strconv.Itoa(cr.Bank))
+    set("spectators", cr.Spectators)
+    set("delay", strconv.Itoa(cr.Delay))
+    set("undo", cr.Undo)
+    set("reveal", cr.Reveal)
+    set("async", strconv.FormatBool(cr.Async))
+    set("move", strconv.Itoa(cr.Move))
+    set("private", strconv.FormatBool(cr.Private))
+    return v
+}
+
+type SitRequest struct {
+    Name string
+    Seed string  // the player's part of the shuffle
+    Code string  // a private table's invite code
+}
+
+type SeedRequest struct {
+    Seed string  // the player's part of the next shuffle
+}
+
+type PlayRequest struct {
+    Index int     // of the card among those showing
+    Position int  // 0 for the back, 1 the middle, 2 the front
+}
+
+// An apiAction is something a player does to a game, posted to
+// /games/{id}/{action}.
+type apiAction struct {
+    Summary string
+    Request interface{}  // a pointer to the request body's type, or nil
+    Errors []error       // the rules it can break, for the spec
+    run func(g *poker.GameState, by string, req interface{}) error
+}
+
+var apiActions = map[string]*apiAction{
+    "sit": {"Take a seat", &SitRequest{},
+        []error{errNameRequired, poker.ErrStarted, poker.ErrAlreadySitting, poker.ErrLocked, poker.ErrNeedsCode, poker.ErrFull},
+        func(g *poker.GameState, by string, req interface{}) error {
+            sr := req.(*SitRequest)
+            if strings.TrimSpace(sr.Name) == "" {
+                return errNameRequired
+            }
+            return g.Sit(by, sr.Name, sr.Seed, sr.Code)
+        }},
+    "seed": {"Add your part of the next shuffle, after the server's commitment to it", &SeedRequest{},
+        []error{poker.ErrNotInGame, poker.ErrStarted},
+        func(g *poker.GameState, by string, req interface{}) error {
+            return g.Seed(by, req.(*SeedRequest).Seed)
+        }},
+    "start": {"Deal the hand, once every player has added a seed", nil,
+        []error{poker.ErrNotInGame, poker.ErrStarted, poker.ErrNoPlayers, poker.ErrNoSeed},
+        func(g *poker.GameState, by string, req interface{}) error {
+            if !g.InGame(by) {
+                return poker.ErrNotInGame
+            }
+            return g.Start()
+        }},
+    "play": {"Place a showing card", &PlayRequest{},
+        []error{poker.ErrNotInProgress, poker.ErrNotYourTurn, poker.ErrInvalidPlay},
+        func(g *poker.GameState, by string, req interface{}) error {
+            pr := req.(*PlayRequest)
+            return g.Play(by, pr.Index, pr.Position)
+        }},
+    "newhand": {"Shuffle for the next hand, once this one is over", nil,
+        []error{poker.ErrNotInGame, poker.ErrHandInProgress, poker.ErrMatchOver},
+        func(g *poker.GameState, by string, req interface{}) error {
+            if !g.InGame(by) {
+                return poker.ErrNotInGame
+            }
+            if !g.Finished() {
+                return poker.ErrHandInProgress
+            }
+            return g.NewHand(poker.CryptoSource)
+        }},
+    "leave": {"Give up your seat and stop watching", nil,
+        []error{poker.ErrNotInGame, poker.ErrHandInProgress},
+        func(g *poker.GameState, by string, req interface{}) error {
+            return g.Leave(by)
+        }},
+}
+
+// apiGames serves /games, /games/{id} and /games/{id}/{action}.
+func apiGames(w http.ResponseWriter, r *http.Request) {
+    rest := strings.Trim(strings.TrimPrefix(r.URL.Path, apiPrefix + "/games"), "/")
+    parts := strings.Split(rest, "/")
+    switch {
+        case rest == "":
+            if allow(w, r, "POST") {
+                apiCreate(w, r)
+            }
+        case len(parts) == 1:
+            if allow(w, r, "GET") {
+                apiState(w, r, parts[0])
+            }
+        case len(parts) == 2:
+            action := apiActions[parts[1]]
+            if action == nil {
+                apiFail(w, &APIError{"no_such_action", "No such action " + parts[1], http.StatusNotFound})
+            } else if allow(w, r, "POST") {
+                apiAct(w, r, parts[0], action)
+            }
+        default:
+            apiFail(w, &APIError{"not_found", "Not found", http.StatusNotFound})
+    }
+}
+
+func apiLoad(c appengine.Context, id string) (*poker.GameState, error) {
+    if _, err := datastore.DecodeKey(id); err != nil {
+        return nil, errNoSuchGame
+    }
+    g, err := poker.LoadGameIn(c, id)
+    if err == datastore.ErrNoSuchEntity {
+        return nil, errNoSuchGame
+    }
+    return g, err
+}
+
+func apiCreate(w http.ResponseWriter, r *http.Request) {
+    cr := &CreateRequest{}
+    if err := decodeBody(r, cr); err != nil {
+        apiFail(w, err)
+        return
+    }
+    // The form's parsers read the request's fields from here.
+    r.Form = cr.form()
+    g, err := newTable(r)
+    if err != nil {
+        apiFail(w, rejected(err))
+        return
+    }
+    if err = g.Save(r); err != nil {
+        apiFail(w, err)
+        return
+    }
+    w.Header().Set("Location", apiPrefix + "/games/" + g.Id())
+    writeJSON(w, http.StatusCreated, g.ClientState(g.Owner))
+}
+
+func apiState(w http.ResponseWriter, r *http.Request, id string) {
+    c := appengine.NewContext(r)
+    g, err := apiLoad(c, id)
+    if err != nil {
+        apiFail(w, err)
+        return
+    }
+    writeJSON(w, http.StatusOK, g.ClientState(user.Current(c).Email))
+}
+
+// apiAct runs action on the game, and follows up as the page's handlers do:
+// the ledger, written with the game, then the clock and notices for a new
+// turn, and the game sent to everyone watching.
+func apiAct(w http.ResponseWriter, r *http.Request, id string, action *apiAction) {
+    c := appengine.NewContext(r)
+    by := user.Current(c).Email
+    var req interface{}
+    if action.Request != nil {
+        req = reflect.New(reflect.TypeOf(action.Request).Elem()).Interface()
+        if err := decodeBody(r, req); err != nil {
+            apiFail(w, err)
+            return
+        }
+    }
+    // The game is loaded afresh each time the transaction is tried, so the
+    // action is applied once, to the game as stored.
+    var g *poker.GameState
+    var dealt int
+    err := datastore.RunInTransaction(c, func(c appengine.Context) error {
+        var err error
+        if g, err = apiLoad(c, id); err != nil {
+            return err
+        }
+        dealt = g.DeckPos
+        played := g.HandsPlayed
+        if err := action.run(g, by, req); err != nil {
+            return rejected(err)
+        }
+        if g.HandsPlayed != played {
+            if err := recordHand(c, g); err != nil {
+                return err
+            }
+        }
+        return g.SaveIn(c)
+    }, nil)
+    if err != nil {
+        apiFail(w, err)
+        return
+    }
+    // The action is done; anything failing from here on is only logged.
+    if g.Started() && g.DeckPos != dealt {
+        if err = turnStarted(c, r, g); err != nil {
+            c.Errorf("starting turn: %v", err)
+        }
+    }
+    if err = broadcastState(c, g); err != nil {
+        c.Errorf("broadcasting: %v", err)
+    }
+    writeJSON(w, http.StatusOK, g.ClientState(by))
+}
@@ -0,0 +1,244 @@
+package ui
+
+import (
+    "appengine"
+    "appengine/socket"
+    "appengine/taskqueue"
+    "appengine/urlfetch"
+    "appengine/user"
+    "encoding/json"
+    "errors"
+    "html/template"
+    "net"
+    "net/http"
+    "net/smtp"
+    "net/url"
+    "os"
+    "poker"
+    "strconv"
+    "strings"
+    "time"
+)
+
+const myGamesLimit = 50
+
+// parseAsync reads whether the table is a correspondence game, and the
+// hours each move may take.
+func parseAsync(r *http.Request) (bool, time.Duration, error) {
+    if r.FormValue("async") == "" {
+        return false, 0, nil
+    }
+    var move time.Duration
+    if h := r.FormValue("move"); h != "" {
+        hours, err := strconv.Atoi(h)
+        if err != nil {
+            return false, 0, errors.New("Bad move time")
+        }
+        move = time.Duration(hours) * time.Hour
+    }
+    return true, move, nil
+}
+
+// mailSender is the address turn notices are mailed from: MAIL_SENDER if
+// set, or else the app's own.
+func mailSender(c appengine.Context) string {
+    if sender := os.Getenv("MAIL_SENDER"); sender != "" {
+        return sender
+    }
+    return "noreply@" + appengine.AppID(c) + ".appspotmail.com"
+}
+
+// mailNotifier is how turn notices are mailed: through the SMTP server at
+// SMTP_ADDR if set, logging in as SMTP_USER with SMTP_PASSWORD if those are
+// too, or else through App Engine's mail service.
+func mailNotifier(c appengine.Context) poker.Notifier {
+    addr := os.Getenv("SMTP_ADDR")
+    if addr == "" {
+        return &poker.MailNotifier{Context: c, Sender: mailSender(c)}
+    }
+    n := &poker.SMTPNotifier{Addr: addr, From: mailSender(c), Dial: func(addr string) (net.Conn, error) {
+        return socket.Dial(c, "tcp", addr)
+    }}
+    if u := os.Getenv("SMTP_USER"); u != "" {
+        host, _, _ := net.SplitHostPort(addr)
+        n.Auth = smtp.PlainAuth("", u, os.Getenv("SMTP_PASSWORD"), host)
+    }
+    return n
+}
+
+// siteBase is the address links in notices are made from: BASE_URL if set,
+// or else the scheme and host the request came in on.
+func siteBase(r *http.Request) string {
+    if base := os.Getenv("BASE_URL"); base != "" {
+        return strings.TrimRight(base, "/")
+    }
+    scheme := "http"
+    if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+        scheme = "https"
+    }
+    return scheme + "://" + r.Host
+}
+
+// notifyTurn enqueues a task to tell the player to act in a correspondence
+// game that it is their turn, so that a slow mail server or webhook does not
+// hold up the move.  A notice that can't be queued is logged, not returned:
+// the move it follows has been made.
+func notifyTurn(c appengine.Context, r *http.Request, g *poker.GameState) {
+    if !g.Async || g.Notice("") == nil {
+        return
+    }
+    t := taskqueue.NewPOSTTask("/tasks/notify", url.Values{
+        "id": {g.Id()},
+        "dealt": {strconv.Itoa(g.DeckPos)},
+        "base": {siteBase(r)},
+    })
+    if _, err := taskqueue.Add(c, t, ""); err != nil {
+        c.Errorf("queueing notice: %v", err)
+    }
+}
+
+// sendNotice sends the notice queued by notifyTurn, in whichever ways the
+// player has asked for, unless the turn has been played in the meantime.
+// Failures are logged rather than retried, so that one notifier failing
+// does not repeat the others.
+func sendNotice(w http.ResponseWriter, r *http.Request) {
+    c := appengine.NewContext(r)
+    g, err := poker.LoadGame(r.FormValue("id"), r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    n := g.Notice(r.FormValue("base"))
+    if n == nil || strconv.Itoa(g.DeckPos) != r.FormValue("dealt") {
+        return
+    }
+    settings, err := poker.LoadNotifySettings(n.Player, r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    notifiers := make(poker.Notifiers, 0)
+    if settings.Email {
+        notifiers = append(notifiers, mailNotifier(c))
+    }
+    if settings.Webhook != "" {
+        notifiers = append(notifiers, &poker.WebhookNotifier{URL: settings.Webhook, Client: urlfetch.Client(c)})
+    }
+    if err := notifiers.Notify(n); err != nil {
+        c.Errorf("notifying %s: %v", n.Player, err)
+    }
+}
+
+// turnStarted is called once a new turn has been dealt and saved.
+func turnStarted(c appengine.Context, r *http.Request, g *poker.GameState) error {
+    if err := scheduleTimeout(c, g); err != nil {
+        return err
+    }
+    notifyTurn(c, r, g)
+    return nil
+}
+
+func myGamesJSON(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-type", "application/json")
+    c := appengine.NewContext(r)
+    tables, err := poker.MyGames(user.Current(c).Email, myGamesLimit, r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    if err := json.NewEncoder(w).Encode(tables); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
+
+func myGames(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-type", "text/html; charset=utf-8")
+    if err := myGamesTemplate.Execute(w, nil); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
+
+// notifySettings shows and, when posted, saves how the player hears about
+// their turns.
+func notifySettings(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-type", "text/html; charset=utf-8")
+    c := appengine.NewContext(r)
+    settings, err := poker.LoadNotifySettings(user.Current(c).Email, r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    if r.Method == "POST" {
+        settings.Email = r.FormValue("email") != ""
+        if err := settings.SetWebhook(strings.TrimSpace(r.FormValue("webhook"))); err != nil {
+            http.Error(w, err.Error(), http.StatusBadRequest)
+            return
+        }
+        if err := settings.Save(r); err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+    }
+    if err := notifyTemplate.Execute(w, settings); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
+
+var myGamesTemplate = template.Must(template.New("mygames").Parse(myGamesTemplateHTML))
+
+const myGamesTemplateHTML = `
+<html>
+  <head><title>My games</title></head>
+  <body>
+    <a href="/lobby">Lobby</a> | <a href="/notify">Notifications</a>
+    <h3>Your turn</h3><div id=myturn></div>
+    <h3>Waiting for others</h3><div id=waiting></div>
+    <script>
+    function row(t) {
+      return '<tr><td>' + t['Variant'] + '</td><td>' + t['Rules'] + '</td><td>' + t['Phase'] +
+        (t['Async'] ? ' (correspondence)' : '') + '</td><td><a href="/game?id=' + t['Id'] + '">Open</a></td></tr>';
+    }
+    function load() {
+      var xhReq = new XMLHttpRequest();
+      xhReq.open("GET", "/mygames.json", true);
+      xhReq.onreadystatechange = function() {
+        if (xhReq.readyState != 4 || xhReq.status != 200) {
+          return;
+        }
+        var tables = JSON.parse(xhReq.responseText) || [];
+        var mine = '<table>', others = '<table>';
+        for (var i = 0; i < tables.length; i++) {
+          if (tables[i]['MyTurn']) {
+            mine += row(tables[i]);
+          } else {
+            others += row(tables[i]);
+          }
+        }
+        document.getElementById('myturn').innerHTML = mine + '</table>';
+        document.getElementById('waiting').innerHTML = others + '</table>';
+      }
+      xhReq.send(null);
+    }
+    load();
+    setInterval(load, 30000);
+    </script>
+  </body>
+</html>
+`
+
+var notifyTemplate = template.Must(template.New("notify").Parse(notifyTemplateHTML))
+
+const notifyTemplateHTML = `
+<html>
+  <head><title>Notifications</title></head>
+  <body>
+    <a href="/mygames">My games</a>
+    <form method=post action=/notify>
+    When it is my turn in a correspondence game:<br>
+    <input type=checkbox name=email value=1 {{if .Email}}checked{{end}}> email me at {{.Player}}<br>
+    post to this webhook (http or https): <input type=text name=webhook size=50 value="{{.Webhook}}"><br>
+    <input type=submit value=Save>
+    </form>
+  </body>
+</html>
+`
@@ -0,0 +1,23 @@
+package ui
+
+import (
+    "net/http"
+    "poker"
+)
+
+// chat sends a message, or with emote set one of the canned emotes.  name is
+// what a spectator goes by; players chat under their seat's name.
+func chat(w http.ResponseWriter, r *http.Request) {
+    tableAction(w, r, func(g *poker.GameState, by string) error {
+        if e := r.FormValue("emote"); e != "" {
+            return g.Emote(by, r.FormValue("name"), e)
+        }
+        return g.Say(by, r.FormValue("name"), r.FormValue("text"))
+    })
+}
+
+func mute(w http.ResponseWriter, r *http.Request) {
+    tableAction(w, r, func(g *poker.GameState, by string) error {
+        return g.Mute(by, r.FormValue("sender"), r.FormValue("muted") != "0")
+    })
+}
@@ -0,0 +1,227 @@
+package ui
+
+import (
+    "encoding/json"
+    "errors"
+    "html/template"
+    "net/http"
+    "poker"
+    "strconv"
+    "strings"
+)
+
+type equityRequest struct {
+    Game int
+    Hands [][]poker.Card
+    Ranges []poker.Range
+    Board, Dead []poker.Card
+    Spec poker.DeckSpec
+    Trials int
+    Source poker.RandSource
+}
+
+func parseEquityRequest(r *http.Request) (*equityRequest, error) {
+    er := &equityRequest{Trials: poker.EquityTrials, Source: poker.CryptoSource}
+    switch r.FormValue("game") {
+        case "", "holdem":
+            er.Game = poker.Holdem
+        case "omaha":
+            er.Game = poker.Omaha
+        case "omaha8":
+            er.Game = poker.OmahaHiLo
+        default:
+            return nil, errors.New("Unknown game " + r.FormValue("game"))
+    }
+    var err error
+    if er.Spec, err = parseDeck(r); err != nil {
+        return nil, err
+    }
+    for _, line := range lines(r.FormValue("hands")) {
+        cards, err := poker.ParseCards(line)
+        if err != nil {
+            return nil, err
+        }
+        er.Hands = append(er.Hands, cards)
+    }
+    for _, line := range lines(r.FormValue("ranges")) {
+        rg, err := poker.ParseRange(line)
+        if err != nil {
+            return nil, err
+        }
+        er.Ranges = append(er.Ranges, rg)
+    }
+    if er.Hands != nil && er.Ranges != nil {
+        return nil, errors.New("Give either hands or ranges, not both")
+    }
+    if er.Board, err = poker.ParseCards(r.FormValue("board")); err != nil {
+        return nil, err
+    }
+    if er.Dead, err = poker.ParseCards(r.FormValue("dead")); err != nil {
+        return nil, err
+    }
+    // A seed makes a simulation repeatable.
+    if seed := r.FormValue("seed"); seed != "" {
+        n, err := strconv.ParseInt(seed, 10, 64)
+        if err != nil {
+            return nil, errors.New("Bad seed")
+        }
+        er.Source = poker.SeededSource(n)
+    }
+    if t := r.FormValue("trials"); t != "" {
+        if er.Trials, err = strconv.Atoi(t); err != nil || er.Trials <= 0 || er.Trials > 1000000 {
+            return nil, errors.New("Bad number of trials")
+        }
+    }
+    return er, nil
+}
+
+// parseDeck reads the deck ("standard" or "short") along with optional
+// numbers of decks and jokers.
+func parseDeck(r *http.Request) (poker.DeckSpec, error) {
+    var spec poker.DeckSpec
+    switch s := r.FormValue("deck"); s {
+        case "", "standard":
+            spec = poker.StandardDeck
+        case "short":
+            spec = poker.ShortDeck
+        default:
+            return spec, errors.New("Unknown deck " + s)
+    }
+    var err error
+    if s := r.FormValue("decks"); s != "" {
+        if spec.Decks, err = strconv.Atoi(s); err != nil {
+            return spec, errors.New("Bad number of decks")
+        }
+    }
+    if s := r.FormValue("jokers"); s != "" {
+        if spec.Jokers, err = strconv.Atoi(s); err != nil {
+            return spec, errors.New("Bad number of jokers")
+        }
+    }
+    return spec, spec.Valid()
+}
+
+// lines splits a form value into its non-blank lines; ";" also separates.
+func lines(s string) []string {
+    result := make([]string, 0)
+    for _, line := range strings.FieldsFunc(s, func(c rune) bool {
+        return c == '\n' || c == ';'
+    }) {
+        if strings.TrimSpace(line) != "" {
+            result = append(result, line)
+        }
+    }
+    return result
+}
+
+func (er *equityRequest) run() (*poker.EquityResult, error) {
+    if er.Ranges != nil {
+        if er.Game != poker.Holdem {
+            return nil, errors.New("Ranges are only for Hold'em")
+        }
+        return poker.RangeEquity(er.Spec, er.Ranges, er.Board, er.Dead, er.Trials, er.Source)
+    }
+    return poker.Equity(er.Game, er.Spec, er.Hands, er.Board, er.Dead, er.Trials, er.Source)
+}
+
+func equityJSON(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-type", "application/json")
+    er, err := parseEquityRequest(r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    result, err := er.run()
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    if err := json.NewEncoder(w).Encode(result); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
+
+type equityRow struct {
+    Cards []poker.Card
+    Range string
+    Win, Tie, Equity float64
+}
+
+type equityPage struct {
+    Hands, Ranges, Board, Dead, Game, Deck string
+    Rows []equityRow
+    Trials int
+    Exact bool
+    Error string
+}
+
+func equity(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-type", "text/html; charset=utf-8")
+    page := &equityPage{
+        Hands: r.FormValue("hands"),
+        Ranges: r.FormValue("ranges"),
+        Board: r.FormValue("board"),
+        Dead: r.FormValue("dead"),
+        Game: r.FormValue("game"),
+        Deck: r.FormValue("deck"),
+    }
+    if page.Hands != "" || page.Ranges != "" {
+        er, err := parseEquityRequest(r)
+        if err == nil {
+            var result *poker.EquityResult
+            if result, err = er.run(); err == nil {
+                for i, hand := range er.Hands {
+                    page.Rows = append(page.Rows, equityRow{hand, "", result.Win[i], result.Tie[i], result.Equity[i]})
+                }
+                for i, rg := range lines(page.Ranges) {
+                    page.Rows = append(page.Rows, equityRow{nil, rg, result.Win[i], result.Tie[i], result.Equity[i]})
+                }
+                page.Trials, page.Exact = result.Trials, result.Exact
+            }
+        }
+        if err != nil {
+            page.Error = err.Error()
+        }
+    }
+    if err := equityTemplate.Execute(w, page); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
+
+var equityTemplate = template.Must(template.New("equity").Parse(equityTemplateHTML))
+
+const equityTemplateHTML = `
+<html>
+  <head><title>Equity</title></head>
+  <body>
+    <form method=get action=/equity>
+    Game: <select name=game>
+      <option value=holdem>Hold'em</option>
+      <option value=omaha {{if eq .Game "omaha"}}selected{{end}}>Omaha</option>
+      <option value=omaha8 {{if eq .Game "omaha8"}}selected{{end}}>Omaha Hi/Lo</option>
+    </select>
+    Deck: <select name=deck>
+      <option value=standard>52 cards</option>
+      <option value=short {{if eq .Deck "short"}}selected{{end}}>Short deck (6+)</option>
+    </select><br>
+    Hands (one per line, e.g. AsKs):<br>
+    <textarea name=hands rows=6 cols=20>{{.Hands}}</textarea><br>
+    or Hold'em ranges (one per line, e.g. QQ+, AKs):<br>
+    <textarea name=ranges rows=6 cols=40>{{.Ranges}}</textarea><br>
+    Board: <input type=text name=board value="{{.Board}}"><br>
+    Dead: <input type=text name=dead value="{{.Dead}}"><br>
+    <input type=submit value=Calculate>
+    </form>
+    {{if .Error}}<b>{{.Error}}</b>{{end}}
+    {{if .Rows}}
+    <table>
+      <tr><th>Hand</th><th>Win</th><th>Tie</th><th>Equity</th></tr>
+      {{range .Rows}}
+      <tr><td>{{range .Cards}}{{.HTML}} {{end}}{{.Range}}</td><td>{{printf "%.2f" .Win}}%</td><td>{{printf "%.2f" .Tie}}%</td><td>{{printf "%.2f" .Equity}}%</td></tr>
+      {{end}}
+    </table>
+    {{.Trials}} boards {{if .Exact}}enumerated{{else}}sampled{{end}}
+    {{end}}
+  </body>
+</html>
+`
@@ -5,6 +5,7 @@ import (
     "appengine/channel"
     "appengine/datastore"
     "appengine/user"
+    "encoding/json"
     "errors"
     "fmt"
     "html/template"
@@ -17,31 +18,134 @@ import (
 func init() {
     http.HandleFunc("/", pick)
     http.HandleFunc("/compare", compare)
+    http.HandleFunc("/equity", equity)
+    http.HandleFunc("/equity.json", equityJSON)
     http.HandleFunc("/create", createGame)
     http.HandleFunc("/game", goToGame)
     http.HandleFunc("/play", play)
     http.HandleFunc("/sit", sit)
     http.HandleFunc("/start", start)
+    http.HandleFunc("/seed", seed)
     http.HandleFunc("/restart", restart)
+    http.HandleFunc("/verify", verify)
+    http.HandleFunc("/ledger", ledger)
+    http.HandleFunc("/lobby", lobby)
+    http.HandleFunc("/lobby.json", lobbyJSON)
+    http.HandleFunc("/ledger.csv", ledgerCSV)
+    http.HandleFunc("/join", joinByInvite)
+    http.HandleFunc("/reserve", reserve)
+    http.HandleFunc("/kick", kick)
+    http.HandleFunc("/lock", lock)
+    http.HandleFunc("/standup", standUp)
+    http.HandleFunc("/leave", leave)
+    http.HandleFunc("/sitout", sitOut)
+    http.HandleFunc("/tasks/timeout", timeout)
+    http.HandleFunc("/tasks/balances", updateBalances)
+    http.HandleFunc("/tasks/notify", sendNotice)
+    http.HandleFunc("/mygames", myGames)
+    http.HandleFunc("/mygames.json", myGamesJSON)
+    http.HandleFunc("/notify", notifySettings)
+    http.HandleFunc("/tasks/spectate", catchUp)
+    http.HandleFunc("/chat", chat)
+    http.HandleFunc("/mute", mute)
+    http.HandleFunc("/undo", undo)
+    http.HandleFunc(apiPrefix + "/games", apiGames)
+    http.HandleFunc(apiPrefix + "/games/", apiGames)
+    http.HandleFunc(apiPrefix + "/openapi.json", apiSpec)
 }
 
 func createGame(w http.ResponseWriter, r *http.Request) {
-    /*n := r.FormValue("n")
-    if n == "" {
-        n = "1"
-    }
-    i, err := strconv.Atoi(n);
+    g, err := newTable(r)
     if err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
-    }*/
-    g := poker.NewGame(0)
-    err := g.Save(r);
+        http.Error(w, err.Error(), http.StatusBadRequest)
+        return
+    }
+    err = g.Save(r);
     if err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
     }
     http.Redirect(w, r, "/game?id=" + g.Id(), http.StatusFound)
 }
 
+// newTable sets up a table, owned by the current user, from the lobby's
+// create form.  Any error is in the form.
+func newTable(r *http.Request) (*poker.GameState, error) {
+    spec, err := parseDeck(r)
+    if err != nil {
+        return nil, err
+    }
+    g, err := poker.NewGame(0, spec, poker.CryptoSource)
+    if err != nil {
+        return nil, err
+    }
+    if g.Match, err = parseMatch(r); err != nil {
+        return nil, err
+    }
+    if g.Stakes, err = parseStakes(r); err != nil {
+        return nil, err
+    }
+    turn, bank, err := parseTimer(r)
+    if err == nil {
+        err = g.SetTimer(turn, bank)
+    }
+    if err != nil {
+        return nil, err
+    }
+    view, delay, err := parseSpectators(r)
+    if err == nil {
+        err = g.SetSpectators(view, delay)
+    }
+    if err != nil {
+        return nil, err
+    }
+    if g.UndoPolicy, err = parseUndo(r); err != nil {
+        return nil, err
+    }
+    if g.PlacedReveal, err = parseReveal(r); err != nil {
+        return nil, err
+    }
+    async, move, err := parseAsync(r)
+    if err == nil && async {
+        err = g.SetAsync(move)
+    }
+    if err != nil {
+        return nil, err
+    }
+    g.Owner = user.Current(appengine.NewContext(r)).Email
+    if r.FormValue("private") != "" {
+        if err = g.MakePrivate(poker.InviteTaken(r)); err != nil {
+            return nil, err
+        }
+    }
+    return g, nil
+}
+
+// parseMatch reads how long a match should last: "hands" or "points" with a
+// target, or "open" to play until someone leaves.  With no match parameter
+// the game is a series of unscored hands, as before.
+func parseMatch(r *http.Request) (*poker.Match, error) {
+    length := poker.UntilLeave
+    switch r.FormValue("match") {
+        case "":
+            return nil, nil
+        case "open":
+        case "hands":
+            length = poker.FixedHands
+        case "points":
+            length = poker.FirstTo
+        default:
+            return nil, errors.New("Unknown match length " + r.FormValue("match"))
+    }
+    target := 0
+    if t := r.FormValue("target"); t != "" {
+        var err error
+        if target, err = strconv.Atoi(t); err != nil {
+            return nil, errors.New("Bad match target")
+        }
+    }
+    return poker.NewMatch(length, target)
+}
+
 func start(w http.ResponseWriter, r *http.Request) {
     c := appengine.NewContext(r)
     id := r.FormValue("id")
@@ -53,13 +157,19 @@ func start(w http.ResponseWriter, r *http.Request) {
         http.Error(w, "You are not in this game", http.StatusInternalServerError)
     }
     err = datastore.RunInTransaction(c, func(c appengine.Context) error {
-        g.NextTurn()
+        if err := g.Start(); err != nil {
+            return err
+        }
         return g.Save(r)
     }, nil);
     if err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
         return
     }
+    if err = turnStarted(c, r, g); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
     err = broadcastState(c, g)
     if err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -81,9 +191,15 @@ func restart(w http.ResponseWriter, r *http.Request) {
         http.Error(w, "Game is not finished", http.StatusInternalServerError)
     }
     err = datastore.RunInTransaction(c, func(c appengine.Context) error {
-        g.NewHand()
+        if err := g.NewHand(poker.CryptoSource); err != nil {
+            return err
+        }
         return g.Save(r)
     }, nil);
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
     err = broadcastState(c, g)
     if err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -91,22 +207,22 @@ func restart(w http.ResponseWriter, r *http.Request) {
     }
 }
 
-func addWatcher(gs *poker.GameState, uid string) bool {
-    for _, watcher := range gs.Watchers {
-        if watcher == uid {
-            return false
-        }
+// defineNames writes the names of every card in the game's deck, jokers and
+// extra decks included, keyed by card id.
+func defineNames(w http.ResponseWriter, spec poker.DeckSpec) {
+    fmt.Fprint(w, "<script>var names = {}; var codes = {};")
+    fmt.Fprintf(w, "names[%d] = '??'; codes[%d] = '??';", poker.Hidden, poker.Hidden)
+    for _, card := range spec.Ordered() {
+        fmt.Fprintf(w, "names[%d] = '%s';", card, card.String())
+        fmt.Fprintf(w, "codes[%d] = '%s';", card, card.Code())
     }
-    gs.Watchers = append(gs.Watchers, uid)
-    return true
+    fmt.Fprint(w, "</script>")
 }
 
-func defineNames(w http.ResponseWriter) {
-    fmt.Fprint(w, "<script>var names = [];")
-    for i := 0; i < 52; i++ {
-        fmt.Fprintf(w, "names[%d] = '%s';", i, poker.Card(i).String())
-    }
-    fmt.Fprint(w, "</script>")
+// defineEmotes writes the emotes a player can send.
+func defineEmotes(w http.ResponseWriter) {
+    b, _ := json.Marshal(poker.Emotes)
+    fmt.Fprintf(w, "<script>var emotes = %s;</script>", b)
 }
 
 func sit(w http.ResponseWriter, r *http.Request) {
@@ -122,7 +238,7 @@ func sit(w http.ResponseWriter, r *http.Request) {
         if name == "" {
             return errors.New("Please choose a name")
         }
-        return g.Sit(u.Email, name)
+        return g.Sit(u.Email, name, r.FormValue("seed"), r.FormValue("code"))
     }, nil);
     if err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -139,6 +255,14 @@ func sit(w http.ResponseWriter, r *http.Request) {
     }
 }
 
+// seed takes the player's part of the next shuffle.  The page sends a fresh
+// one for as long as the table is waiting on it.
+func seed(w http.ResponseWriter, r *http.Request) {
+    tableAction(w, r, func(g *poker.GameState, by string) error {
+        return g.Seed(by, r.FormValue("seed"))
+    })
+}
+
 func goToGame(w http.ResponseWriter, r *http.Request) {
     g, err := poker.LoadGame(r.FormValue("id"), r)
     if err != nil {
@@ -152,7 +276,7 @@ func goToGame(w http.ResponseWriter, r *http.Request) {
         http.Error(w, err.Error(), http.StatusInternalServerError)
         return
     }
-    if addWatcher(g, u.Email) {
+    if g.Watch(u.Email) {
         err := g.Save(r);
         if err != nil {
             http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -163,7 +287,8 @@ func goToGame(w http.ResponseWriter, r *http.Request) {
         http.Error(w, err.Error(), http.StatusInternalServerError)
         return
     }
-    defineNames(w);
+    defineNames(w, g.Spec);
+    defineEmotes(w)
     if err := gameTemplate.Execute(w, json); err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
     }
@@ -173,62 +298,45 @@ func goToGame(w http.ResponseWriter, r *http.Request) {
 func play(w http.ResponseWriter, r *http.Request) {
     c := appengine.NewContext(r)
     u := user.Current(c)
-    g, err := poker.LoadGame(r.FormValue("id"), r)
+    idx, err := strconv.Atoi(r.FormValue("idx"))
     if err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
         return
     }
-    idx, err := strconv.Atoi(r.FormValue("idx"))
+    pos, err := strconv.Atoi(r.FormValue("pos"))
     if err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
         return
     }
-    pos, err := strconv.Atoi(r.FormValue("pos"))
+    // The game is loaded afresh on every attempt, so that a retried
+    // transaction plays the card once, and the finished hand goes into the
+    // ledger with the game.
+    var g *poker.GameState
+    var dealt int
+    err = datastore.RunInTransaction(c, func(c appengine.Context) error {
+        var err error
+        if g, err = poker.LoadGameIn(c, r.FormValue("id")); err != nil {
+            return err
+        }
+        dealt = g.DeckPos
+        if err = g.Play(u.Email, idx, pos); err != nil {
+            return err
+        }
+        if err = recordHand(c, g); err != nil {
+            return err
+        }
+        return g.SaveIn(c)
+    }, nil)
     if err != nil {
         http.Error(w, err.Error(), http.StatusInternalServerError)
         return
     }
-    if pos < poker.Back || pos > poker.Front || idx < 0 || idx >= len(g.Showing) {
-        http.Error(w, "Invalid play", http.StatusInternalServerError)
-    }
-    if g.Players[g.Turn] != u.Email {
-        http.Error(w, "It is not your turn!", http.StatusInternalServerError)
-    }
-    hand := g.Hands[g.Turn]
-    card := g.Showing[idx]
-    switch (pos) {
-        case poker.Back:
-            if hand.Back.Count() >= 5 {
-                http.Error(w, "Invalid play", http.StatusInternalServerError)
-                return
-            }
-            g.Hands[g.Turn].Back = hand.Back.Add(card)
-        case poker.Middle:
-            if hand.Middle.Count() >= 5 {
-                http.Error(w, "Invalid play", http.StatusInternalServerError)
-                return
-            }
-            g.Hands[g.Turn].Middle = hand.Middle.Add(card)
-        case poker.Front:
-            if hand.Front.Count() >= 3 {
-                http.Error(w, "Invalid play", http.StatusInternalServerError)
-                return
-            }
-            g.Hands[g.Turn].Front = hand.Front.Add(card)
-        default:
-            http.Error(w, "Invalid play", http.StatusInternalServerError)
+    // A new turn has started if more cards were dealt.
+    if g.DeckPos != dealt {
+        if err = turnStarted(c, r, g); err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
             return
-    }
-    // Remove the card that was placed
-    copy(g.Showing[idx:], g.Showing[idx+1:]) 
-    g.Showing = g.Showing[:len(g.Showing)-1]
-    if len(g.Showing) == 0 {
-        // Someone else's turn
-        g.NextTurn()
-    }
-    if err = g.Save(r); err != nil {
-        http.Error(w, err.Error(), http.StatusInternalServerError)
-        return
+        }
     }
     err = broadcastState(c, g)
     if err != nil {
@@ -239,7 +347,7 @@ func play(w http.ResponseWriter, r *http.Request) {
 }
 
 func broadcastState(c appengine.Context, g *poker.GameState) error {
-    for _, watcher := range g.Watchers {
+    for _, watcher := range g.Audience() {
         json, err := g.ClientState(watcher).JSON()
         if err != nil {
             return err
@@ -249,7 +357,7 @@ func broadcastState(c appengine.Context, g *poker.GameState) error {
             c.Errorf("sending Game: %v", err)
         }
     }
-    return nil
+    return scheduleCatchUp(c, g)
 }
 
 func compare(w http.ResponseWriter, r *http.Request) {
@@ -264,8 +372,8 @@ func compare(w http.ResponseWriter, r *http.Request) {
             h2 = h2.Add(poker.Card(i))
         }
     }
-    fmt.Fprintf(w, "Hand 1: %s<br>", h1)
-    fmt.Fprintf(w, "Hand 2: %s<br>", h2)
+    fmt.Fprintf(w, "Hand 1: %s (%s)<br>", h1, h1.Describe())
+    fmt.Fprintf(w, "Hand 2: %s (%s)<br>", h2, h2.Describe())
     c := h1.Compare(h2)
     if c < 0 {
         fmt.Fprint(w, "Hand 2 wins!")
@@ -274,6 +382,7 @@ func compare(w http.ResponseWriter, r *http.Request) {
     } else {
         fmt.Fprint(w, "Tie!")
     }
+    fmt.Fprintf(w, " (%s)", poker.CompareExplain(h1, h2))
 }
 
 func pick(w http.ResponseWriter, r *http.Request) {
@@ -292,93 +401,65 @@ const gameTemplateHTML = `
   <body>
   <script type="text/javascript" src="/_ah/channel/jsapi"></script>
   <div id="content">
-  <div id="hand0" style="display:none">
-  <b><span id=hand0name>Anonymous</span>:</b> [<span id=hand0royalties>-</span>]<br>
-  Back: <span id="hand0back"></span><br>
-  Middle: <span id="hand0middle"></span><br>
-  Front: <span id="hand0front"></span><br>
-  <div id="hand0fault"><b>Fault!</b></div>
-  <div id="hand0next">Next Card: <span id="hand0nextCard"></span>
-    <input type=button value=Back onclick='play(0)'><input type=button value=Middle onclick='play(1)'><input type=button value=Front onclick='play(2)'>
-  </div>
-  </div>
-  <br>
-  <div id="hand1" style="display:none">
-  <b><span id=hand1name>Anonymous</span>:</b> [<span id=hand1royalties>-</span>]<br>
-  Back: <span id="hand1back"></span><br>
-  Middle: <span id="hand1middle"></span><br>
-  Front: <span id="hand1front"></span><br>
-  <div id="hand1fault"><b>Fault!</b></div>
-  <div id="hand1next">Next Card: <span id="hand1nextCard"></span>
-    <input type=button value=Back onclick='play(0)'><input type=button value=Middle onclick='play(1)'><input type=button value=Front onclick='play(2)'>
-  </div>
-  </div>
-  <br>
-  <div id="hand2" style="display:none">
-  <b><span id=hand2name>Anonymous</span>:</b> [<span id=hand2royalties>-</span>]<br>
-  Back: <span id="hand2back"></span><br>
-  Middle: <span id="hand2middle"></span><br>
-  Front: <span id="hand2front"></span><br>
-  <div id="hand2fault"><b>Fault!</b></div>
-  <div id="hand2next">Next Card: <span id="hand2nextCard"></span>
-    <input type=button value=Back onclick='play(0)'><input type=button value=Middle onclick='play(1)'><input type=button value=Front onclick='play(2)'>
-  </div>
   </div>
-  <br>
-  <div id="hand3" style="display:none">
-  <b><span id=hand3name>Anonymous</span>:</b> [<span id=hand3royalties>-</span>]<br>
-  Back: <span id="hand3back"></span><br>
-  Middle: <span id="hand3middle"></span><br>
-  Front: <span id="hand3front"></span><br>
-  <div id="hand3fault"><b>Fault!</b></div>
-  <div id="hand3next">Next Card: <span id="hand3nextCard"></span>
-    <input type=button value=Back onclick='play(0)'><input type=button value=Middle onclick='play(1)'><input type=button value=Front onclick='play(2)'>
+  <div id=join><input type=button onclick="join()" value=Join> as <input id=joinName type=text value=Anonymous>
+    with shuffle seed <input id=joinSeed type=text size=34>
+    <span id=joinCode>invite code <input id=joinCodeValue type=text size=8></span></div>
+  <div id=owner>
+    <span id=invite></span>
+    Reserve a seat for <input id=reserveFor type=text> <input type=button onclick="reserve()" value=Reserve>
+    <input type=button id=lockButton onclick="lock()" value=Lock>
   </div>
-  </div>
-  </div>
-  <div id=join><input type=button onclick="join()" value=Join> as <input id=joinName type=text value=Anonymous></div>
   <div id=start><input type=button onclick="start()" value=Start></div>
   <div id=restart><input type=button onclick="restart()" value=Shuffle></div>
+  <div id=seatControls>
+    <input type=button id=sitOutButton onclick="sitOut()" value="Sit out">
+    <span id=standControls><input type=button onclick="tableAction('/standup?')" value="Stand up">
+    <input type=button onclick="leave()" value=Leave></span>
+  </div>
+  <div id=undoControls><input type=button id=undoButton onclick="tableAction('/undo?')" value=Undo>
+    <span id=undoRequest></span></div>
+  <div id=clock></div>
+  <div id=spectators></div>
+  <div id=explanations></div>
+  <div id=showdown></div>
+  <div id=scoreboard></div>
+  <div id=ledger></div>
+  <div id=fairness></div>
+  <div id=chat style="height:12em;overflow:auto;border:1px solid #ccc"></div>
+  <form onsubmit="say(); return false">
+    <input id=chatText type=text size=60 maxlength=300> <input type=submit value=Say>
+    <span id=emotes></span>
+  </form>
   <script>
-    var hands = [document.getElementById('hand0'),
-      document.getElementById('hand1'),
-      document.getElementById('hand2'),
-      document.getElementById('hand3')];
-
-    var backs = [document.getElementById('hand0back'),
-      document.getElementById('hand1back'),
-      document.getElementById('hand2back'),
-      document.getElementById('hand3back')];
-      
-    var middles = [document.getElementById('hand0middle'),
-      document.getElementById('hand1middle'),
-      document.getElementById('hand2middle'),
-      document.getElementById('hand3middle')];
-      
-    var fronts = [document.getElementById('hand0front'),
-      document.getElementById('hand1front'),
-      document.getElementById('hand2front'),
-      document.getElementById('hand3front')];
-      
-    var nexts = [document.getElementById('hand0next'),
-      document.getElementById('hand1next'),
-      document.getElementById('hand2next'),
-      document.getElementById('hand3next')];
-      
-    var faults = [document.getElementById('hand0fault'),
-      document.getElementById('hand1fault'),
-      document.getElementById('hand2fault'),
-      document.getElementById('hand3fault')];
-      
-    var royalties = [document.getElementById('hand0royalties'),
-      document.getElementById('hand1royalties'),
-      document.getElementById('hand2royalties'),
-      document.getElementById('hand3royalties')];
-      
-    var playerNames = [document.getElementById('hand0name'),
-      document.getElementById('hand1name'),
-      document.getElementById('hand2name'),
-      document.getElementById('hand3name')];
+    var hands = [], backs = [], middles = [], fronts = [], nexts = [],
+      faults = [], royalties = [], playerNames = [];
+
+    // seat makes the elements for hand i, as many as the deck has room for.
+    function seat(i) {
+      while (hands.length <= i) {
+        var n = hands.length;
+        var div = document.createElement('div');
+        div.style.display = 'none';
+        div.innerHTML = '<b><span>Anonymous</span>:</b> [<span>-</span>]<br>' +
+          'Back: <span></span><br>' +
+          'Middle: <span></span><br>' +
+          'Front: <span></span><br>' +
+          '<div><b>Fault!</b></div>' +
+          '<div></div><br>';
+        document.getElementById('content').appendChild(div);
+        var spans = div.getElementsByTagName('span');
+        var divs = div.getElementsByTagName('div');
+        hands[n] = div;
+        playerNames[n] = spans[0];
+        royalties[n] = spans[1];
+        backs[n] = spans[2];
+        middles[n] = spans[3];
+        fronts[n] = spans[4];
+        faults[n] = divs[0];
+        nexts[n] = divs[1];
+      }
+    }
   
     function play(idx, pos) {
        var xhReq = new XMLHttpRequest();
@@ -395,7 +476,9 @@ const gameTemplateHTML = `
   
     function join() {
        var xhReq = new XMLHttpRequest();
-       xhReq.open("GET", "/sit?name=" + document.getElementById('joinName').value + "&id=" + game_id, false);
+       xhReq.open("GET", "/sit?name=" + document.getElementById('joinName').value +
+           "&seed=" + encodeURIComponent(document.getElementById('joinSeed').value) +
+           "&code=" + encodeURIComponent(document.getElementById('joinCodeValue').value) + "&id=" + game_id, false);
        xhReq.onreadystatechange = function() {
          if (xhReq.status != 200) {
            alert(xhReq.responseText);
@@ -419,6 +502,102 @@ const gameTemplateHTML = `
        xhReq.send(null);
     }
   
+    function tableAction(url) {
+       var xhReq = new XMLHttpRequest();
+       xhReq.open("GET", url + "&id=" + game_id, false);
+       xhReq.onreadystatechange = function() {
+         if (xhReq.status != 200) {
+           alert(xhReq.responseText);
+         }
+       }
+       xhReq.send(null);
+    }
+
+    function reserve() {
+      tableAction("/reserve?player=" + encodeURIComponent(document.getElementById('reserveFor').value));
+    }
+
+    function kick(seat) {
+      tableAction("/kick?seat=" + seat);
+    }
+
+    var sittingOut = false;
+    function sitOut() {
+      tableAction("/sitout?out=" + (sittingOut ? 0 : 1));
+    }
+
+    function leave() {
+      tableAction("/leave?");
+      window.location = "/lobby";
+    }
+
+    function say() {
+      var text = document.getElementById('chatText');
+      tableAction("/chat?text=" + encodeURIComponent(text.value) +
+        "&name=" + encodeURIComponent(document.getElementById('joinName').value));
+      text.value = '';
+    }
+
+    function emote(e) {
+      tableAction("/chat?emote=" + encodeURIComponent(e) +
+        "&name=" + encodeURIComponent(document.getElementById('joinName').value));
+    }
+
+    function muteSender(sender) {
+      tableAction("/mute?sender=" + sender);
+    }
+
+    function escapeHTML(s) {
+      return s.replace(/&/g, '&amp;').replace(/</g, '&lt;').replace(/>/g, '&gt;');
+    }
+
+    function showEmotes() {
+      var html = '';
+      for (var i = 0; i < emotes.length; i++) {
+        html += '<input type=button value="' + emotes[i] + '" onclick="emote(emotes[' + i + '])">';
+      }
+      document.getElementById('emotes').innerHTML = html;
+    }
+    showEmotes();
+
+    function showChat(messages) {
+      var elt = document.getElementById('chat');
+      var html = '';
+      for (var i = 0; messages && i < messages.length; i++) {
+        var m = messages[i];
+        var name = escapeHTML(m['Name']) + (m['Spectator'] ? ' (watching)' : '');
+        html += m['Emote'] ? '<i>' + name + ': ' + escapeHTML(m['Text']) + '</i>' :
+          '<b>' + name + ':</b> ' + escapeHTML(m['Text']);
+        html += ' <a href="#" onclick="muteSender(\'' + m['Sender'] + '\'); return false">mute</a><br>';
+      }
+      elt.innerHTML = html;
+      elt.scrollTop = elt.scrollHeight;
+    }
+
+    function showUndo(state) {
+      var request = state['Undo'];
+      document.getElementById('undoButton').style.display = state['CanTakeBack'] ||
+        (state['UndoPolicy'] == 1 && !request && state['InGame']) ? 'inline' : 'none';
+      document.getElementById('undoControls').style.display =
+        state['Started'] && !state['Finished'] && state['UndoPolicy'] != 2 ? 'block' : 'none';
+      var html = '';
+      if (request) {
+        html = escapeHTML(state['Players'][request['Seat']]) + ' asks to undo their last card.' +
+          ' The cards dealt since will be dealt again, and they have seen the ones played.';
+        if (state['InGame'] && state['Seat'] != request['Seat'] &&
+            (request['Approved'] || []).indexOf(state['Seat']) < 0) {
+          html += ' <input type=button onclick="tableAction(\'/undo?answer=1\')" value=Allow>' +
+            ' <input type=button onclick="tableAction(\'/undo?answer=0\')" value=Refuse>';
+        }
+      }
+      document.getElementById('undoRequest').innerHTML = html;
+    }
+
+    var locked = false;
+    function lock() {
+      tableAction("/lock?locked=" + (locked ? 0 : 1));
+    }
+
     function restart() {
        var xhReq = new XMLHttpRequest();
        xhReq.open("GET", "/restart?id=" + game_id, false);
@@ -439,19 +618,26 @@ const gameTemplateHTML = `
       return false;
     }
     
-    function showCards(hand, elt, winner) {
+    function showCards(hand, elt, winner, description) {
       //alert(hand);
       var html = '';
       if (hand != null) {
-        for (var i = 0; i < hand['Royalty']['Cards'].length; i++) {
-          html += names[hand['Royalty']['Cards'][i]] + ' ';
-        }
-        if (hand['Kickers'] != null) {
-          for (var i = 0; i < hand['Kickers'].length; i++) {
-            html += names[hand['Kickers'][i]] + ' ';
+        // Jokers show as the cards they stand for.
+        var subs = (hand['Subs'] || []).slice();
+        var cards = hand['Royalty']['Cards'].concat(hand['Kickers'] || []);
+        for (var i = 0; i < cards.length; i++) {
+          var j = subs.indexOf(cards[i]);
+          if (j >= 0) {
+            subs.splice(j, 1);
+            html += 'Joker=' + names[cards[i]] + ' ';
+          } else {
+            html += names[cards[i]] + ' ';
           }
         }
       }
+      if (hand != null && description) {
+        html += '(' + description + ')';
+      }
       if (winner) {
         html += " *";
       }
@@ -459,11 +645,25 @@ const gameTemplateHTML = `
     }
   
     function showHand(state, i) {
+      seat(i);
       var hand = state['Hands'][i];
       hands[i].style.display = 'block';
       nexts[i].style.display = 'none';
       faults[i].style.display = 'none';
-      playerNames[i].childNodes[0].data = state['Players'][i];
+      var stack = state['Stacks'] ? state['Stacks'][i] : null;
+      playerNames[i].childNodes[0].data = state['Players'][i] + (stack != null ? ' (' + stack + ' points)' : '');
+      if (hand == null) {
+        // An empty seat, or a player sitting out or out of points.
+        var out = stack != null && stack <= 0 ? ' (out of points)' : ' (sitting out)';
+        playerNames[i].childNodes[0].data = state['Players'][i] ? state['Players'][i] + out : 'Empty seat';
+        royalties[i].childNodes[0].data = '-';
+        backs[i].innerHTML = middles[i].innerHTML = fronts[i].innerHTML = '';
+        return;
+      }
+      if (state['Owner'] && !state['Started']) {
+        nexts[i].innerHTML = '<input type=button value=Kick onclick="kick(' + i + ')">';
+        nexts[i].style.display = 'block';
+      }
       royalties[i].childNodes[0].data = state['Royalties'][i];
       if (state['Faults'] && state['Faults'][i]) {
         faults[i].style.display = 'block';
@@ -481,32 +681,233 @@ const gameTemplateHTML = `
             }
           }
         }
+        var odds = state['Odds'];
+        if (odds) {
+          html += '<br>Foul chance: ' + Math.round(odds['Probability'] * 100) + '%';
+          if (!odds['Exact']) {
+            html += ' (est.)';
+          }
+          if (odds['Fouled']) {
+            html += ' <b>Dead hand!</b>';
+          }
+        }
         nexts[i].innerHTML = html;
         nexts[i].style.display = 'block';
         //nextCards[i].innerHTML = names[state['Card']]
       }
-      showCards(hand['Back'], backs[i], isWinner(i, state['BackWinners']));
-      showCards(hand['Middle'], middles[i], isWinner(i, state['MiddleWinners']));
-      showCards(hand['Front'], fronts[i], isWinner(i, state['FrontWinners']));
+      var descriptions = state['Descriptions'] ? state['Descriptions'][i] : [];
+      showCards(hand['Back'], backs[i], isWinner(i, state['BackWinners']), descriptions[0]);
+      showCards(hand['Middle'], middles[i], isWinner(i, state['MiddleWinners']), descriptions[1]);
+      showCards(hand['Front'], fronts[i], isWinner(i, state['FrontWinners']), descriptions[2]);
     }
     
+    function randomSeed() {
+      var bytes = new Uint8Array(16);
+      window.crypto.getRandomValues(bytes);
+      var seed = '';
+      for (var i = 0; i < bytes.length; i++) {
+        seed += (bytes[i] < 16 ? '0' : '') + bytes[i].toString(16);
+      }
+      return seed;
+    }
+    document.getElementById('joinSeed').value = randomSeed();
+    var codeParam = /[?&]code=([^&]*)/.exec(window.location.search);
+    if (codeParam) {
+      document.getElementById('joinCodeValue').value = decodeURIComponent(codeParam[1]);
+    }
+
+    function showFairness(fairness) {
+      var elt = document.getElementById('fairness');
+      if (!fairness) {
+        elt.innerHTML = '';
+        return;
+      }
+      var html = 'Server seed commitment: ' + fairness['Commitment'];
+      if (fairness['ServerSeed']) {
+        var dealt = '';
+        for (var i = 0; i < fairness['Dealt'].length; i++) {
+          dealt += codes[fairness['Dealt'][i]];
+        }
+        html += '<br>Server seed: ' + fairness['ServerSeed'] +
+          ' <a href="/verify?commitment=' + fairness['Commitment'] +
+          '&server=' + fairness['ServerSeed'] +
+          '&clients=' + encodeURIComponent((fairness['ClientSeeds'] || []).join('\n')) +
+          '&dealt=' + dealt + '&deck=' + (fairness['Spec']['Low'] > 0 ? 'short' : 'standard') +
+          '&decks=' + fairness['Spec']['Decks'] + '&jokers=' + fairness['Spec']['Jokers'] + '">Verify</a>';
+      }
+      elt.innerHTML = html;
+    }
+
+    function showExplanations(explanations) {
+      var html = '';
+      var rows = ['Back', 'Middle', 'Front'];
+      for (var i = 0; explanations && i < explanations.length; i++) {
+        if (explanations[i]) {
+          html += rows[i] + ': ' + explanations[i] + '<br>';
+        }
+      }
+      document.getElementById('explanations').innerHTML = html;
+    }
+
+    function showShowdown(state) {
+      var showdown = state['Showdown'];
+      var elt = document.getElementById('showdown');
+      if (!showdown) {
+        elt.innerHTML = '';
+        return;
+      }
+      var rows = ['Back', 'Middle', 'Front'];
+      var html = '<table border=1>';
+      for (var i = 0; i < showdown['Matchups'].length; i++) {
+        var m = showdown['Matchups'][i];
+        var a = escapeHTML(state['Players'][m['Players'][0]]), b = escapeHTML(state['Players'][m['Players'][1]]);
+        html += '<tr><th colspan=5>' + a + ' vs ' + b + ': ' + (m['Points'] > 0 ? '+' : '') + m['Points'];
+        for (var k = 0; k < 2; k++) {
+          var name = k == 0 ? a : b;
+          if (m['Fouled'][k]) html += ' (' + name + ' fouled)';
+          if (m['Scooped'][k]) html += ' (' + name + ' scoops)';
+        }
+        html += '</th></tr>';
+        for (var j = 0; j < m['Rows'].length; j++) {
+          var row = m['Rows'][j];
+          var result = row['Result'] > 0 ? a : (row['Result'] < 0 ? b : 'Tie');
+          html += '<tr><td>' + rows[row['Pos']] + '</td><td>' + row['Descriptions'][0] +
+            ' [' + row['Royalties'][0] + ']</td><td>' + row['Descriptions'][1] +
+            ' [' + row['Royalties'][1] + ']</td><td>' + result + '</td><td>' + row['Explanation'] + '</td></tr>';
+        }
+      }
+      html += '</table>Totals: ';
+      for (var i = 0; i < showdown['Totals'].length; i++) {
+        html += escapeHTML(state['Players'][i]) + ' ' + showdown['Totals'][i] + ' ';
+      }
+      elt.innerHTML = html;
+    }
+
+    function showScoreboard(state) {
+      var match = state['Match'];
+      var elt = document.getElementById('scoreboard');
+      if (!match || !state['Players']) {
+        elt.innerHTML = '';
+        return;
+      }
+      var html = '<table border=1><tr><th>Hand</th>';
+      for (var i = 0; i < state['Players'].length; i++) {
+        html += '<th>' + escapeHTML(state['Players'][i]) + '</th>';
+      }
+      html += '</tr>';
+      var results = match['Results'] || [];
+      for (var h = 0; h < results.length; h++) {
+        html += '<tr><td>' + (h + 1) + '</td>';
+        for (var i = 0; i < state['Players'].length; i++) {
+          var points = i < results[h]['Points'].length ? results[h]['Points'][i] : '';
+          html += '<td>' + points + (results[h]['Fouled'][i] ? ' (foul)' : '') + '</td>';
+        }
+        html += '</tr>';
+      }
+      html += '<tr><th>Total</th>';
+      for (var i = 0; i < state['Players'].length; i++) {
+        html += '<th>' + (state['Scores'] && i < state['Scores'].length ? state['Scores'][i] : 0) + '</th>';
+      }
+      html += '</tr></table>';
+      if (match['Length'] == 1) {
+        html += 'Match of ' + match['Target'] + ' hands. ';
+      } else if (match['Length'] == 2) {
+        html += 'First to ' + match['Target'] + ' points. ';
+      }
+      if (match['Over']) {
+        html += '<b>Match over.</b>';
+      }
+      elt.innerHTML = html;
+    }
+
+    // The clock counts down to the deadline of the player to act.
+    var deadline = null, clockPlayer = '';
+    function tick() {
+      var elt = document.getElementById('clock');
+      if (!deadline) {
+        elt.textContent = '';
+        return;
+      }
+      var left = Math.max(0, Math.round((deadline - new Date()) / 1000));
+      elt.textContent = clockPlayer + ' has ' + left + 's to play';
+    }
+    setInterval(tick, 1000);
+
+    function showClock(state) {
+      deadline = null;
+      if (state['TurnTime'] && state['Started'] && !state['Finished'] && state['Deadline']) {
+        deadline = new Date(state['Deadline']);
+        clockPlayer = state['MyTurn'] ? 'You' : state['Players'][state['Turn']];
+      }
+      tick();
+    }
+
     var game_id;
+    var needSeed = false, seeding = false;
+
+    // sendSeed sends a fresh seed for the next shuffle, and tries again
+    // while the table still needs one from us.
+    function sendSeed() {
+      seeding = true;
+      var xhReq = new XMLHttpRequest();
+      xhReq.open("GET", "/seed?seed=" + randomSeed() + "&id=" + game_id, true);
+      xhReq.onreadystatechange = function() {
+        if (xhReq.readyState != 4) {
+          return;
+        }
+        seeding = false;
+        if (xhReq.status != 200) {
+          setTimeout(function() { if (needSeed && !seeding) sendSeed(); }, 1000);
+        }
+      }
+      xhReq.send(null);
+    }
   
     function handle(state) {
       game_id = state['GameId'];
+      needSeed = state['NeedSeed'];
+      if (needSeed && !seeding) {
+        sendSeed();
+      }
       var hands = state['Hands'];
       document.getElementById('join').style.display = 'none';
-      if (!state['Started'] && (!state['Players'] || state['Players'].length < 4)) {
+      var seated = (state['Players'] || []).filter(function(name) { return name != ''; }).length;
+      if (!state['Started'] && !state['InGame'] && seated < state['MaxPlayers']) {
         document.getElementById('join').style.display = 'block';
       }
+      document.getElementById('joinCode').style.display = state['Private'] && !state['Owner'] ? 'inline' : 'none';
+      locked = state['Locked'];
+      document.getElementById('owner').style.display = state['Owner'] && !state['Started'] ? 'block' : 'none';
+      document.getElementById('lockButton').value = locked ? 'Unlock' : 'Lock';
+      document.getElementById('invite').innerHTML = state['InviteCode'] ?
+        'Invite code: <b>' + state['InviteCode'] + '</b>' : '';
+      document.getElementById('seatControls').style.display = state['InGame'] ? 'block' : 'none';
+      sittingOut = state['InGame'] && state['SittingOut'][state['Seat']];
+      document.getElementById('sitOutButton').value = sittingOut ? 'Sit back in' : 'Sit out';
+      document.getElementById('standControls').style.display =
+        !state['Started'] || state['Finished'] ? 'inline' : 'none';
       document.getElementById('start').style.display = 'none';
       if (!state['Started'] && state['InGame']) {
         document.getElementById('start').style.display = 'block';
       }
       document.getElementById('restart').style.display = 'none';
-      if (state['Finished'] && state['InGame']) {
+      if (state['Finished'] && state['InGame'] && !(state['Match'] && state['Match']['Over'])) {
         document.getElementById('restart').style.display = 'block';
       }
+      showClock(state);
+      showUndo(state);
+      showChat(state['Chat']);
+      document.getElementById('spectators').innerHTML = (state['Spectating'] ? 'You are watching. ' : '') +
+        (state['Spectators'] == 1 ? '1 spectator' : state['Spectators'] + ' spectators');
+      showFairness(state['Fairness']);
+      showExplanations(state['Explanations']);
+      showShowdown(state);
+      showScoreboard(state);
+      if (state['Stakes']) {
+        document.getElementById('ledger').innerHTML = 'Stakes: ' + state['Stakes']['PointValue'] +
+          ' a point' + (state['Stakes']['BuyIn'] ? ', buy-in ' + state['Stakes']['BuyIn'] : '') +
+          ' <a href="/ledger?id=' + state['GameId'] + '">Ledger</a>';
+      }
       //alert(game_id);
       //alert(hands);
       if (hands) {
@@ -0,0 +1,187 @@
+package ui
+
+import (
+    "appengine"
+    "appengine/taskqueue"
+    "appengine/user"
+    "errors"
+    "html/template"
+    "net/http"
+    "net/url"
+    "poker"
+    "sort"
+    "strconv"
+)
+
+// parseStakes reads the point value and buy-in for a table played for
+// stakes.  With no value the table plays for nothing.
+func parseStakes(r *http.Request) (*poker.Stakes, error) {
+    v := r.FormValue("value")
+    if v == "" {
+        return nil, nil
+    }
+    value, err := strconv.ParseFloat(v, 64)
+    if err != nil {
+        return nil, errors.New("Bad point value")
+    }
+    buyIn := 0
+    if b := r.FormValue("buyin"); b != "" {
+        if buyIn, err = strconv.Atoi(b); err != nil {
+            return nil, errors.New("Bad buy-in")
+        }
+    }
+    return poker.NewStakes(value, buyIn)
+}
+
+type ledgerRow struct {
+    Name string
+    Net int
+    Stack int
+}
+
+type ledgerHand struct {
+    Hand int
+    From, To string
+    Points int
+}
+
+type ledgerSettlement struct {
+    Text string
+    Amount float64
+}
+
+type ledgerPage struct {
+    Id string
+    Stakes *poker.Stakes
+    Entries []*poker.LedgerEntry
+    Names map[string]string  // by player id
+    Hands []ledgerHand
+    Rows []ledgerRow
+    Settlements []poker.Settlement
+    Settled []ledgerSettlement
+    Balance *poker.Balance
+}
+
+func loadLedger(r *http.Request) (*ledgerPage, error) {
+    id := r.FormValue("id")
+    g, err := poker.LoadGame(id, r)
+    if err != nil {
+        return nil, err
+    }
+    if g.Stakes == nil {
+        return nil, errors.New("This table is not played for stakes")
+    }
+    entries, err := poker.LoadLedger(id, r)
+    if err != nil {
+        return nil, err
+    }
+    // Players are told apart by id; names are only for showing.
+    page := &ledgerPage{Id: id, Stakes: g.Stakes, Entries: entries, Names: poker.Names(entries)}
+    net := poker.Net(entries)
+    for i, player := range g.Players {
+        if player == "" {
+            continue
+        }
+        name, ok := page.Names[player]
+        if !ok {
+            name = g.PlayerNames[i]
+        }
+        page.Rows = append(page.Rows, ledgerRow{name, net[player], g.Stack(i)})
+    }
+    sort.Slice(page.Rows, func(i, j int) bool { return page.Rows[i].Net > page.Rows[j].Net })
+    for _, e := range entries {
+        page.Hands = append(page.Hands, ledgerHand{e.Hand, page.Names[e.From], page.Names[e.To], e.Points})
+    }
+    page.Settlements = poker.Settle(net, g.Stakes.PointValue)
+    for _, s := range page.Settlements {
+        page.Settled = append(page.Settled, ledgerSettlement{s.Named(page.Names), s.Amount})
+    }
+    return page, nil
+}
+
+// recordHand writes a hand just finished to the ledger in the game's
+// transaction c, and enqueues with it the task that brings the players'
+// balances up to date, so that both happen only if the game is saved.
+func recordHand(c appengine.Context, g *poker.GameState) error {
+    if g.Stakes == nil || !g.Finished() {
+        return nil
+    }
+    if err := g.RecordHand(c); err != nil {
+        return err
+    }
+    t := taskqueue.NewPOSTTask("/tasks/balances",
+        url.Values{"id": {g.Id()}, "hand": {strconv.Itoa(g.HandsPlayed)}})
+    _, err := taskqueue.Add(c, t, "")
+    return err
+}
+
+func updateBalances(w http.ResponseWriter, r *http.Request) {
+    hand, err := strconv.Atoi(r.FormValue("hand"))
+    if err != nil {
+        http.Error(w, "Bad hand", http.StatusBadRequest)
+        return
+    }
+    if err = poker.UpdateBalances(r.FormValue("id"), hand, r); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
+
+func ledger(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-type", "text/html; charset=utf-8")
+    page, err := loadLedger(r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    c := appengine.NewContext(r)
+    if page.Balance, err = poker.LoadBalance(user.Current(c).Email, r); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    if err := ledgerTemplate.Execute(w, page); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
+
+func ledgerCSV(w http.ResponseWriter, r *http.Request) {
+    page, err := loadLedger(r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    w.Header().Set("Content-type", "text/csv")
+    w.Header().Set("Content-Disposition", "attachment; filename=ledger.csv")
+    if err := poker.WriteLedgerCSV(w, page.Entries, page.Settlements, page.Names); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
+
+var ledgerTemplate = template.Must(template.New("ledger").Parse(ledgerTemplateHTML))
+
+const ledgerTemplateHTML = `
+<html>
+  <head><title>Ledger</title></head>
+  <body>
+    <a href="/game?id={{.Id}}">Back to the game</a> | <a href="/ledger.csv?id={{.Id}}">Download CSV</a><br>
+    {{.Stakes.PointValue}} a point{{if .Stakes.BuyIn}}, buy-in {{.Stakes.BuyIn}} points{{end}}
+    <table>
+      <tr><th>Player</th><th>Net</th>{{if .Stakes.BuyIn}}<th>Stack</th>{{end}}</tr>
+      {{range .Rows}}
+      <tr><td>{{.Name}}</td><td>{{.Net}}</td>{{if $.Stakes.BuyIn}}<td>{{.Stack}}</td>{{end}}</tr>
+      {{end}}
+    </table>
+    {{if .Settled}}
+    <h3>Settlement</h3>
+    {{range .Settled}}{{.Text}} ({{printf "%.2f" .Amount}})<br>{{end}}
+    {{end}}
+    <h3>Hands</h3>
+    <table>
+      <tr><th>Hand</th><th>From</th><th>To</th><th>Points</th></tr>
+      {{range .Hands}}
+      <tr><td>{{.Hand}}</td><td>{{.From}}</td><td>{{.To}}</td><td>{{.Points}}</td></tr>
+      {{end}}
+    </table>
+    Your balance across all tables: {{.Balance.Points}} points
+  </body>
+</html>
+`
@@ -0,0 +1,133 @@
+package ui
+
+import (
+    "encoding/json"
+    "html/template"
+    "net/http"
+    "poker"
+)
+
+const lobbyLimit = 50
+
+type lobbyState struct {
+    Waiting, Playing, Finished []*poker.TableInfo
+}
+
+func loadLobby(r *http.Request) (*lobbyState, error) {
+    ls := &lobbyState{}
+    var err error
+    if ls.Waiting, err = poker.List(poker.PhaseWaiting, lobbyLimit, r); err != nil {
+        return nil, err
+    }
+    if ls.Playing, err = poker.List(poker.PhasePlaying, lobbyLimit, r); err != nil {
+        return nil, err
+    }
+    if ls.Finished, err = poker.List(poker.PhaseFinished, lobbyLimit, r); err != nil {
+        return nil, err
+    }
+    return ls, nil
+}
+
+func lobbyJSON(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-type", "application/json")
+    ls, err := loadLobby(r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    if err := json.NewEncoder(w).Encode(ls); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
+
+func lobby(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-type", "text/html; charset=utf-8")
+    if err := lobbyTemplate.Execute(w, nil); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
+
+var lobbyTemplate = template.Must(template.New("lobby").Parse(lobbyTemplateHTML))
+
+const lobbyTemplateHTML = `
+<html>
+  <head><title>Lobby</title></head>
+  <body>
+    <a href="/mygames">My games</a><br>
+    <form method=get action=/create>
+    New table: <select name=deck>
+      <option value=standard>52 cards</option>
+      <option value=short>Short deck (6+)</option>
+    </select>
+    Decks: <input type=text name=decks size=2 value=1>
+    Jokers: <input type=text name=jokers size=2 value=0>
+    <select name=match>
+      <option value="">Single hands</option>
+      <option value=open>Open match</option>
+      <option value=hands>Fixed number of hands</option>
+      <option value=points>First to points</option>
+    </select>
+    Target: <input type=text name=target size=4>
+    Point value: <input type=text name=value size=4>
+    Buy-in: <input type=text name=buyin size=4>
+    Turn time (s): <input type=text name=turn size=3>
+    Time bank (s): <input type=text name=bank size=3>
+    Spectators see <select name=spectators>
+      <option value=placed>placed cards</option>
+      <option value=after>hands once they are over</option>
+      <option value=none>no hands</option>
+    </select>
+    delayed by <input type=text name=delay size=3> s
+    Undo <select name=undo>
+      <option value=turn>within a turn</option>
+      <option value=consent>with the others' consent</option>
+      <option value=none>never</option>
+    </select>
+    Placed cards are shown <select name=reveal>
+      <option value=placed>at once</option>
+      <option value=showdown>at showdown</option>
+    </select>
+    <input type=checkbox name=async value=1>Correspondence, with
+    <input type=text name=move size=3> hours a move
+    <input type=checkbox name=private value=1>Private
+    <input type=submit value=Create>
+    </form>
+    <form method=get action=/join>
+    Invite code: <input type=text name=code size=8> <input type=submit value="Find table">
+    </form>
+    <h3>Waiting for players</h3><div id=waiting></div>
+    <h3>In progress</h3><div id=playing></div>
+    <h3>Finished</h3><div id=finished></div>
+    <script>
+    function showTables(id, tables) {
+      var html = '<table><tr><th>Seats</th><th>Variant</th><th>Rules</th><th>Stakes</th><th></th></tr>';
+      for (var i = 0; tables && i < tables.length; i++) {
+        var t = tables[i];
+        html += '<tr><td>' + t['Players'] + '/' + t['MaxPlayers'] + '</td><td>' + t['Variant'] +
+          '</td><td>' + t['Rules'] + '</td><td>' +
+          (t['PointValue'] ? t['PointValue'] + ' a point, buy-in ' + t['BuyIn'] : '-') +
+          '</td><td><a href="/game?id=' + t['Id'] + '">' + (id == 'waiting' ? 'Join' : 'Watch') + '</a></td></tr>';
+      }
+      document.getElementById(id).innerHTML = html + '</table>';
+    }
+
+    // Poll so that the seat counts stay current.
+    function refresh() {
+      var xhReq = new XMLHttpRequest();
+      xhReq.open("GET", "/lobby.json", true);
+      xhReq.onreadystatechange = function() {
+        if (xhReq.readyState == 4 && xhReq.status == 200) {
+          var lobby = JSON.parse(xhReq.responseText);
+          showTables('waiting', lobby['Waiting']);
+          showTables('playing', lobby['Playing']);
+          showTables('finished', lobby['Finished']);
+        }
+      }
+      xhReq.send(null);
+    }
+    refresh();
+    setInterval(refresh, 5000);
+    </script>
+  </body>
+</html>
+`
@@ -0,0 +1,184 @@
+package ui
+
+import (
+    "net/http"
+    "poker"
+    "reflect"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// The API's OpenAPI description.  Its schemas are made from the Go types
+// the API reads and writes, so they can't fall behind them.
+
+type schema map[string]interface{}
+
+var (
+    timeType = reflect.TypeOf(time.Time{})
+    durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// schemaOf is the schema for t.  Named structs go into defs, and are
+// referred to.
+func schemaOf(t reflect.Type, defs map[string]schema) schema {
+    switch {
+        case t == timeType:
+            return schema{"type": "string", "format": "date-time"}
+        case t == durationType:
+            return schema{"type": "integer", "description": "nanoseconds"}
+    }
+    switch t.Kind() {
+        case reflect.Ptr:
+            return schemaOf(t.Elem(), defs)
+        case reflect.Bool:
+            return schema{"type": "boolean"}
+        case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+            reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+            return schema{"type": "integer"}
+        case reflect.Float32, reflect.Float64:
+            return schema{"type": "number"}
+        case reflect.String:
+            return schema{"type": "string"}
+        case reflect.Slice, reflect.Array:
+            if t.Elem().Kind() == reflect.Uint8 {
+                return schema{"type": "string", "format": "byte"}
+            }
+            return schema{"type": "array", "items": schemaOf(t.Elem(), defs)}
+        case reflect.Map:
+            return schema{"type": "object", "additionalProperties": schemaOf(t.Elem(), defs)}
+        case reflect.Struct:
+            if t.Name() == "" {
+                return structSchema(t, defs)
+            }
+            if _, ok := defs[t.Name()]; !ok {
+                defs[t.Name()] = schema{}  // in case t refers to itself
+                defs[t.Name()] = structSchema(t, defs)
+            }
+            return schema{"$ref": "#/components/schemas/" + t.Name()}
+    }
+    return schema{}
+}
+
+// structSchema lists the fields encoding/json would write, embedded structs'
+// included.
+func structSchema(t reflect.Type, defs map[string]schema) schema {
+    props := schema{}
+    var addFields func(t reflect.Type)
+    addFields = func(t reflect.Type) {
+        for i := 0; i < t.NumField(); i++ {
+            f := t.Field(i)
+            ft := f.Type
+            if ft.Kind() == reflect.Ptr {
+                ft = ft.Elem()
+            }
+            if f.Anonymous && ft.Kind() == reflect.Struct && f.Tag.Get("json") == "" {
+                addFields(ft)
+                continue
+            }
+            if f.PkgPath != "" {
+                continue
+            }
+            name := f.Name
+            if tag := strings.Split(f.Tag.Get("json"), ","); tag[0] == "-" {
+                continue
+            } else if tag[0] != "" {
+                name = tag[0]
+            }
+            props[name] = schemaOf(f.Type, defs)
+        }
+    }
+    addFields(t)
+    return schema{"type": "object", "properties": props}
+}
+
+func ref(v interface{}, defs map[string]schema) schema {
+    return schemaOf(reflect.TypeOf(v), defs)
+}
+
+func jsonContent(s schema) schema {
+    return schema{"application/json": schema{"schema": s}}
+}
+
+// errorResponses describes the errors an operation can answer with: the
+// given rules broken, along with extra codes by status.
+func errorResponses(rules []error, extra map[int][]string, defs map[string]schema) schema {
+    codes := make(map[int][]string)
+    for status, c := range extra {
+        codes[status] = append(codes[status], c...)
+    }
+    for _, err := range rules {
+        rule := ruleErrors[err]
+        codes[rule.status] = append(codes[rule.status], rule.code)
+    }
+    responses := schema{}
+    for status, c := range codes {
+        sort.Strings(c)
+        responses[strconv.Itoa(status)] = schema{
+            "description": http.StatusText(status) + ": " + strings.Join(c, ", "),
+            "content": jsonContent(ref(&APIError{}, defs)),
+        }
+    }
+    return responses
+}
+
+func okResponse(defs map[string]schema) schema {
+    return schema{
+        "description": "The game as the caller now sees it",
+        "content": jsonContent(ref(&poker.ClientGameState{}, defs)),
+    }
+}
+
+func apiDocument() schema {
+    defs := make(map[string]schema)
+    idParam := schema{"name": "id", "in": "path", "required": true, "schema": schema{"type": "string"}}
+    notFound := map[int][]string{http.StatusNotFound: {errNoSuchGame.Code}}
+
+    create := errorResponses(nil, map[int][]string{http.StatusBadRequest: {"bad_json", "invalid"}}, defs)
+    create["201"] = okResponse(defs)
+    state := errorResponses(nil, notFound, defs)
+    state["200"] = okResponse(defs)
+    paths := schema{
+        "/games": schema{"post": schema{
+            "summary": "Create a table, owned by the caller",
+            "requestBody": schema{"content": jsonContent(ref(&CreateRequest{}, defs))},
+            "responses": create,
+        }},
+        "/games/{id}": schema{"get": schema{
+            "summary": "The game as the caller sees it",
+            "parameters": []schema{idParam},
+            "responses": state,
+        }},
+    }
+    for name, action := range apiActions {
+        extra := map[int][]string{
+            http.StatusNotFound: {errNoSuchGame.Code},
+            http.StatusBadRequest: {"invalid"},
+            http.StatusConflict: {"busy"},
+        }
+        op := schema{
+            "summary": action.Summary,
+            "parameters": []schema{idParam},
+        }
+        if action.Request != nil {
+            extra[http.StatusBadRequest] = append(extra[http.StatusBadRequest], "bad_json")
+            op["requestBody"] = schema{"content": jsonContent(ref(action.Request, defs))}
+        }
+        responses := errorResponses(action.Errors, extra, defs)
+        responses["200"] = okResponse(defs)
+        op["responses"] = responses
+        paths["/games/{id}/" + name] = schema{"post": op}
+    }
+    return schema{
+        "openapi": "3.0.3",
+        "info": schema{"title": "Chinese Poker", "version": "1"},
+        "servers": []schema{{"url": apiPrefix}},
+        "paths": paths,
+        "components": schema{"schemas": defs},
+    }
+}
+
+func apiSpec(w http.ResponseWriter, r *http.Request) {
+    writeJSON(w, http.StatusOK, apiDocument())
+}
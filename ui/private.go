@@ -0,0 +1,74 @@
+package ui
+
+import (
+    "appengine"
+    "appengine/datastore"
+    "appengine/user"
+    "net/http"
+    "net/url"
+    "poker"
+    "strconv"
+)
+
+// tableAction loads the game, applies f for the current user in a
+// transaction, then saves and sends the new state to everyone watching.
+// The game is loaded afresh on every attempt, so f sees what is stored and
+// a retry applies it once.
+func tableAction(w http.ResponseWriter, r *http.Request, f func(g *poker.GameState, by string) error) {
+    c := appengine.NewContext(r)
+    u := user.Current(c)
+    var g *poker.GameState
+    status := http.StatusInternalServerError
+    err := datastore.RunInTransaction(c, func(c appengine.Context) error {
+        var err error
+        if g, err = poker.LoadGameIn(c, r.FormValue("id")); err != nil {
+            return err
+        }
+        if err := f(g, u.Email); err != nil {
+            status = http.StatusForbidden
+            return err
+        }
+        status = http.StatusInternalServerError
+        return g.SaveIn(c)
+    }, nil)
+    if err != nil {
+        http.Error(w, err.Error(), status)
+        return
+    }
+    if err = broadcastState(c, g); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
+
+func reserve(w http.ResponseWriter, r *http.Request) {
+    tableAction(w, r, func(g *poker.GameState, by string) error {
+        return g.Reserve(by, r.FormValue("player"))
+    })
+}
+
+func kick(w http.ResponseWriter, r *http.Request) {
+    seat, err := strconv.Atoi(r.FormValue("seat"))
+    if err != nil {
+        http.Error(w, "Bad seat", http.StatusBadRequest)
+        return
+    }
+    tableAction(w, r, func(g *poker.GameState, by string) error {
+        return g.Kick(by, seat)
+    })
+}
+
+func lock(w http.ResponseWriter, r *http.Request) {
+    tableAction(w, r, func(g *poker.GameState, by string) error {
+        return g.Lock(by, r.FormValue("locked") == "1")
+    })
+}
+
+// joinByInvite sends the player to the private table with the invite code.
+func joinByInvite(w http.ResponseWriter, r *http.Request) {
+    id, err := poker.FindByInvite(r.FormValue("code"), r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusNotFound)
+        return
+    }
+    http.Redirect(w, r, "/game?id=" + id + "&code=" + url.QueryEscape(r.FormValue("code")), http.StatusFound)
+}
@@ -0,0 +1,24 @@
+package ui
+
+import (
+    "net/http"
+    "poker"
+)
+
+func standUp(w http.ResponseWriter, r *http.Request) {
+    tableAction(w, r, func(g *poker.GameState, by string) error {
+        return g.StandUp(by)
+    })
+}
+
+func leave(w http.ResponseWriter, r *http.Request) {
+    tableAction(w, r, func(g *poker.GameState, by string) error {
+        return g.Leave(by)
+    })
+}
+
+func sitOut(w http.ResponseWriter, r *http.Request) {
+    tableAction(w, r, func(g *poker.GameState, by string) error {
+        return g.SitOut(by, r.FormValue("out") == "1")
+    })
+}
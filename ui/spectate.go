@@ -0,0 +1,69 @@
+package ui
+
+import (
+    "appengine"
+    "appengine/channel"
+    "appengine/taskqueue"
+    "errors"
+    "net/http"
+    "net/url"
+    "poker"
+    "strconv"
+    "time"
+)
+
+// parseSpectators reads what spectators see ("placed", "after" or "none")
+// and their delay in seconds.
+func parseSpectators(r *http.Request) (int, time.Duration, error) {
+    view := poker.SpectatePlaced
+    switch r.FormValue("spectators") {
+        case "", "placed":
+        case "after":
+            view = poker.SpectateAfterHand
+        case "none":
+            view = poker.SpectateNone
+        default:
+            return 0, 0, errors.New("Unknown spectator view " + r.FormValue("spectators"))
+    }
+    var delay time.Duration
+    if d := r.FormValue("delay"); d != "" {
+        seconds, err := strconv.Atoi(d)
+        if err != nil {
+            return 0, 0, errors.New("Bad spectator delay")
+        }
+        delay = time.Duration(seconds) * time.Second
+    }
+    return view, delay, nil
+}
+
+// scheduleCatchUp asks for the spectators to be sent the game again once
+// their delay has passed, so that they see the moves they were kept from.
+func scheduleCatchUp(c appengine.Context, g *poker.GameState) error {
+    at := g.Caught()
+    if at.IsZero() || len(g.Spectators) == 0 {
+        return nil
+    }
+    t := taskqueue.NewPOSTTask("/tasks/spectate", url.Values{"id": {g.Id()}})
+    t.ETA = at
+    _, err := taskqueue.Add(c, t, "")
+    return err
+}
+
+func catchUp(w http.ResponseWriter, r *http.Request) {
+    c := appengine.NewContext(r)
+    g, err := poker.LoadGame(r.FormValue("id"), r)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    for _, spectator := range g.Spectators {
+        json, err := g.ClientState(spectator).JSON()
+        if err != nil {
+            http.Error(w, err.Error(), http.StatusInternalServerError)
+            return
+        }
+        if err = channel.Send(c, spectator + g.Id(), json); err != nil {
+            c.Errorf("sending Game: %v", err)
+        }
+    }
+}
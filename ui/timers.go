@@ -0,0 +1,88 @@
+package ui
+
+import (
+    "appengine"
+    "appengine/datastore"
+    "appengine/taskqueue"
+    "errors"
+    "net/http"
+    "net/url"
+    "poker"
+    "strconv"
+    "time"
+)
+
+// parseTimer reads the seconds a player has for each turn and for their time
+// bank.  With no turn time the table is untimed.
+func parseTimer(r *http.Request) (turn, bank time.Duration, err error) {
+    seconds := func(name string) (time.Duration, error) {
+        v := r.FormValue(name)
+        if v == "" {
+            return 0, nil
+        }
+        n, err := strconv.Atoi(v)
+        if err != nil {
+            return 0, errors.New("Bad " + name + " time")
+        }
+        return time.Duration(n) * time.Second, nil
+    }
+    if turn, err = seconds("turn"); err != nil {
+        return
+    }
+    bank, err = seconds("bank")
+    return
+}
+
+// scheduleTimeout asks for the turn to be checked when its clock runs out.
+// A check that finds the turn already played does nothing.
+func scheduleTimeout(c appengine.Context, g *poker.GameState) error {
+    if g.Deadline.IsZero() {
+        return nil
+    }
+    t := taskqueue.NewPOSTTask("/tasks/timeout", url.Values{"id": {g.Id()}})
+    t.ETA = g.Deadline
+    _, err := taskqueue.Add(c, t, "")
+    return err
+}
+
+// timeout plays the showing cards for a player whose time is up.
+func timeout(w http.ResponseWriter, r *http.Request) {
+    c := appengine.NewContext(r)
+    // The turn may have been played since the task was scheduled, or while
+    // a retried transaction waited, so the game is loaded and its clock
+    // checked afresh on each attempt.
+    var g *poker.GameState
+    played := false
+    err := datastore.RunInTransaction(c, func(c appengine.Context) error {
+        var err error
+        if g, err = poker.LoadGameIn(c, r.FormValue("id")); err != nil {
+            return err
+        }
+        played = false
+        if !g.TimedOut() {
+            return nil
+        }
+        if err := g.AutoPlay(); err != nil {
+            return err
+        }
+        played = true
+        if err := recordHand(c, g); err != nil {
+            return err
+        }
+        return g.SaveIn(c)
+    }, nil)
+    if err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    if !played {
+        return
+    }
+    if err = turnStarted(c, r, g); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+        return
+    }
+    if err = broadcastState(c, g); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
@@ -0,0 +1,48 @@
+package ui
+
+import (
+    "appengine"
+    "errors"
+    "net/http"
+    "poker"
+)
+
+// parseUndo reads how far players may take back their placements: "turn",
+// "consent" or "none".
+func parseUndo(r *http.Request) (int, error) {
+    switch r.FormValue("undo") {
+        case "", "turn":
+            return poker.UndoTurn, nil
+        case "consent":
+            return poker.UndoConsent, nil
+        case "none":
+            return poker.UndoNone, nil
+    }
+    return 0, errors.New("Unknown undo setting " + r.FormValue("undo"))
+}
+
+// undo takes back the player's last placement, or asks the others to let
+// them if their turn is over.  answer=1 or answer=0 instead approves or
+// rejects someone else's request.
+func undo(w http.ResponseWriter, r *http.Request) {
+    var game *poker.GameState
+    rewound := false
+    tableAction(w, r, func(g *poker.GameState, by string) error {
+        dealt := g.DeckPos
+        var err error
+        if a := r.FormValue("answer"); a != "" {
+            err = g.AnswerUndo(by, a != "0")
+        } else {
+            err = g.RequestUndo(by)
+        }
+        game, rewound = g, g.DeckPos != dealt
+        return err
+    })
+    // Going back past a deal starts that turn again, with a new clock.
+    if rewound {
+        c := appengine.NewContext(r)
+        if err := turnStarted(c, r, game); err != nil {
+            c.Errorf("%v", err)
+        }
+    }
+}
@@ -0,0 +1,88 @@
+package ui
+
+import (
+    "html/template"
+    "net/http"
+    "poker"
+    "strings"
+)
+
+type verifyPage struct {
+    Commitment, Server, Clients, Dealt, Deck, Decks, Jokers string
+    Checked bool
+    Error string
+}
+
+func verify(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-type", "text/html; charset=utf-8")
+    page := &verifyPage{
+        Commitment: r.FormValue("commitment"),
+        Server: r.FormValue("server"),
+        Clients: r.FormValue("clients"),
+        Dealt: r.FormValue("dealt"),
+        Deck: r.FormValue("deck"),
+        Decks: r.FormValue("decks"),
+        Jokers: r.FormValue("jokers"),
+    }
+    if page.Server != "" {
+        page.Checked = true
+        err := func() error {
+            spec, err := parseDeck(r)
+            if err != nil {
+                return err
+            }
+            dealt, err := poker.ParseCards(page.Dealt)
+            if err != nil {
+                return err
+            }
+            f := &poker.Fairness{
+                Spec: spec,
+                Commitment: page.Commitment,
+                ServerSeed: page.Server,
+                Dealt: dealt,
+            }
+            // Seeds may be blank, so split on newlines alone.
+            if page.Clients != "" {
+                f.ClientSeeds = splitLines(page.Clients)
+            }
+            return f.Verify()
+        }()
+        if err != nil {
+            page.Error = err.Error()
+        }
+    }
+    if err := verifyTemplate.Execute(w, page); err != nil {
+        http.Error(w, err.Error(), http.StatusInternalServerError)
+    }
+}
+
+func splitLines(s string) []string {
+    return strings.Split(strings.Replace(s, "\r\n", "\n", -1), "\n")
+}
+
+var verifyTemplate = template.Must(template.New("verify").Parse(verifyTemplateHTML))
+
+const verifyTemplateHTML = `
+<html>
+  <head><title>Verify a deal</title></head>
+  <body>
+    <form method=get action=/verify>
+    Deck: <select name=deck>
+      <option value=standard>52 cards</option>
+      <option value=short {{if eq .Deck "short"}}selected{{end}}>Short deck (6+)</option>
+    </select>
+    Decks: <input type=text name=decks size=2 value="{{.Decks}}">
+    Jokers: <input type=text name=jokers size=2 value="{{.Jokers}}"><br>
+    Commitment: <input type=text name=commitment size=70 value="{{.Commitment}}"><br>
+    Server seed: <input type=text name=server size=70 value="{{.Server}}"><br>
+    Client seeds (one per player, in seat order):<br>
+    <textarea name=clients rows=4 cols=40>{{.Clients}}</textarea><br>
+    Cards dealt, in order: <input type=text name=dealt size=70 value="{{.Dealt}}"><br>
+    <input type=submit value=Verify>
+    </form>
+    {{if .Checked}}
+      {{if .Error}}<b>Verification failed:</b> {{.Error}}{{else}}<b>The deal matches the seeds.</b>{{end}}
+    {{end}}
+  </body>
+</html>
+`
@@ -0,0 +1,19 @@
+package ui
+
+import (
+    "errors"
+    "net/http"
+    "poker"
+)
+
+// parseReveal reads when players see each other's placed cards: "placed"
+// as soon as they are placed, or "showdown" once the hand is over.
+func parseReveal(r *http.Request) (int, error) {
+    switch r.FormValue("reveal") {
+        case "", "placed":
+            return poker.RevealAlways, nil
+        case "showdown":
+            return poker.RevealShowdown, nil
+    }
+    return 0, errors.New("Unknown reveal setting " + r.FormValue("reveal"))
+}
@@ -0,0 +1,32 @@
+package ui
+
+import (
+    "net/http/httptest"
+    "poker"
+    "strings"
+    "testing"
+)
+
+func TestParseReveal(t *testing.T) {
+    for form, want := range map[string]int{
+        "": poker.RevealAlways,
+        "reveal=placed": poker.RevealAlways,
+        "reveal=showdown": poker.RevealShowdown,
+    } {
+        r := httptest.NewRequest("POST", "/create", strings.NewReader(form))
+        r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+        if got, err := parseReveal(r); err != nil || got != want {
+            t.Errorf("form %q: reveal %d, %v, want %d", form, got, err, want)
+        }
+    }
+    r := httptest.NewRequest("GET", "/create?reveal=never", nil)
+    if _, err := parseReveal(r); err == nil {
+        t.Errorf("accepted an unknown reveal setting")
+    }
+    // The API's create request reaches the same parser through its form.
+    r = httptest.NewRequest("POST", apiPrefix + "/games", nil)
+    r.Form = (&CreateRequest{Reveal: "showdown"}).form()
+    if got, err := parseReveal(r); err != nil || got != poker.RevealShowdown {
+        t.Errorf("API request: reveal %d, %v", got, err)
+    }
+}
@@ -17,8 +17,12 @@ type UserState struct {
 func GetUserState(r *http.Request) (*UserState, error) {
     c := appengine.NewContext(r)
     //u := user.Current(c)
-    us := &UserState{Deck: poker.NewShuffledDeck(), Position: 0, Hand: poker.ChineseHand{}}
-    _, err := datastore.Put(c, datastore.NewIncompleteKey(c, "UserState", nil), &us)
+    d, err := poker.NewShuffledDeck(poker.CryptoSource)
+    if err != nil {
+        return nil, err
+    }
+    us := &UserState{Deck: d, Position: 0, Hand: poker.ChineseHand{}}
+    _, err = datastore.Put(c, datastore.NewIncompleteKey(c, "UserState", nil), &us)
     if err != nil {
         return nil, err
     }